@@ -13,7 +13,7 @@ func main() {
 		log.Fatal(err)
 	}
 
-	ids, tokens, err := encoding.Encode("Hello World", nil, nil)
+	ids, tokens, err := encoding.Encode("Hello World")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/dlclark/regexp2"
 )
@@ -17,17 +18,18 @@ type coreBPE struct {
 	decoder              map[uint]string
 	specialTokensEncoder map[string]uint
 	specialTokensDecoder map[uint]string
-	tlRegex              *regexp2.Regexp
+	preTokenizer         preTokenizer
 	tlSpecialRegex       *regexp2.Regexp
 	sortedTokenBytes     [][]byte
+	maxTokenValue        uint
 }
 
 // newCoreBPE creates a new CoreBPE instance.
 // It initializes the CoreBPE with the provided encoder, specialTokensEncoder, and pattern.
 func newCoreBPE(encoder map[string]uint, specialTokensEncoder map[string]uint, pattern string) (*coreBPE, error) {
-	regex, err := regexp2.Compile(pattern, regexp2.None)
+	preTokenizer, err := newPreTokenizer(pattern)
 	if err != nil {
-		return nil, fmt.Errorf("error compiling regex: %s", err)
+		return nil, err
 	}
 
 	specialRegexStrs := make([]string, 0, len(specialTokensEncoder))
@@ -35,9 +37,12 @@ func newCoreBPE(encoder map[string]uint, specialTokensEncoder map[string]uint, p
 		specialRegexStrs = append(specialRegexStrs, regexp.QuoteMeta(k))
 	}
 
-	specialRegex, err := regexp2.Compile(strings.Join(specialRegexStrs, "|"), regexp2.None)
-	if err != nil {
-		return nil, fmt.Errorf("error compiling special regex: %s", err)
+	var specialRegex *regexp2.Regexp
+	if len(specialRegexStrs) > 0 {
+		specialRegex, err = regexp2.Compile(strings.Join(specialRegexStrs, "|"), regexp2.None)
+		if err != nil {
+			return nil, fmt.Errorf("error compiling special regex: %s", err)
+		}
 	}
 
 	decoder := make(map[uint]string, len(encoder))
@@ -63,110 +68,229 @@ func newCoreBPE(encoder map[string]uint, specialTokensEncoder map[string]uint, p
 		return bytes.Compare(sortedTokenBytes[i], sortedTokenBytes[j]) < 0
 	})
 
+	var maxTokenValue uint
+
+	for _, tokens := range []map[string]uint{encoder, specialTokensEncoder} {
+		for _, v := range tokens {
+			if v > maxTokenValue {
+				maxTokenValue = v
+			}
+		}
+	}
+
 	return &coreBPE{
 		encoder:              encoder,
 		specialTokensEncoder: specialTokensEncoder,
 		decoder:              decoder,
 		specialTokensDecoder: specialTokensDecoder,
-		tlRegex:              regex,
+		preTokenizer:         preTokenizer,
 		tlSpecialRegex:       specialRegex,
 		sortedTokenBytes:     sortedTokenBytes,
+		maxTokenValue:        maxTokenValue,
 	}, nil
 }
 
+// tokenFunc is called for every encoded token with its ID and its byte range [start, end) in the encoded text.
+type tokenFunc func(id uint, start, end int)
+
 // Encode performs tokenization and encoding of the input text using the Byte Pair Encoding (BPE) algorithm.
 // It takes the input text and a set of allowed special tokens as parameters.
 // It returns the encoded token IDs and corresponding tokens as slices.
 func (bpe *coreBPE) Encode(text string, allowedSpecial map[string]any) ([]uint, []string) {
-	specialRegex := bpe.tlSpecialRegex
-	regex := bpe.tlRegex
+	retIDs := []uint{}
+	retTokens := []string{}
+
+	bpe.encode(text, allowedSpecial, func(id uint, start, end int) {
+		retIDs = append(retIDs, id)
+		retTokens = append(retTokens, text[start:end])
+	})
+
+	return retIDs, retTokens
+}
 
+// EncodeOrdinary performs tokenization and encoding of the input text using the Byte Pair Encoding (BPE) algorithm,
+// treating all tokens as ordinary tokens (not special tokens).
+// It takes the input text as a parameter and returns the encoded token IDs and corresponding tokens as slices.
+func (bpe *coreBPE) EncodeOrdinary(text string) ([]uint, []string) {
 	retIDs := []uint{}
 	retTokens := []string{}
 
-	textLength := len(text)
-	textRunes := []rune(text)
+	var scratch []mergePart
 
-	start := 0
+	bpe.encodeOrdinary(text, 0, len(text), &scratch, func(id uint, start, end int) {
+		retIDs = append(retIDs, id)
+		retTokens = append(retTokens, text[start:end])
+	})
 
-	for {
-		var nextSpecial []int
+	return retIDs, retTokens
+}
 
-		startFind := start
+// AppendEncode appends the token IDs Encode would produce for the input text and the set of allowed
+// special tokens to dst and returns the extended slice. It does not build token strings.
+func (bpe *coreBPE) AppendEncode(dst []uint, text string, allowedSpecial map[string]any) []uint {
+	bpe.encode(text, allowedSpecial, func(id uint, _, _ int) {
+		dst = append(dst, id)
+	})
 
-		for {
-			temp := cut(textRunes, startFind, textLength)
-			nextSpecial = findRegex2StringIndex(temp, specialRegex)
+	return dst
+}
 
-			if nextSpecial != nil {
-				token := cut(textRunes, startFind+nextSpecial[0], startFind+nextSpecial[1])
-				if _, ok := allowedSpecial[token]; ok {
-					break
-				}
+// AppendEncodeOrdinary appends the token IDs EncodeOrdinary would produce for the input text to dst
+// and returns the extended slice. It does not build token strings.
+func (bpe *coreBPE) AppendEncodeOrdinary(dst []uint, text string) []uint {
+	var scratch []mergePart
 
-				startFind += nextSpecial[1]
-			} else {
-				break
-			}
-		}
+	bpe.encodeOrdinary(text, 0, len(text), &scratch, func(id uint, _, _ int) {
+		dst = append(dst, id)
+	})
 
-		end := textLength
+	return dst
+}
+
+// Count returns the number of tokens Encode would produce for the input text and the set of allowed
+// special tokens without building token IDs or token strings.
+func (bpe *coreBPE) Count(text string, allowedSpecial map[string]any) int {
+	var scratch []mergePart
+
+	n, start := 0, 0
+
+	for {
+		nextSpecial := bpe.findNextAllowedSpecial(text, start, allowedSpecial)
+
+		end := len(text)
 		if nextSpecial != nil {
-			end = start + nextSpecial[0]
+			end = nextSpecial[0]
 		}
 
-		for _, mat := range findRegex2AllStringMatchIndex(cut(textRunes, start, end), regex) {
-			piece := cut(textRunes, start+mat[0], start+mat[1])
+		n += bpe.countOrdinary(text[start:end], &scratch)
 
-			if id, ok := bpe.encoder[piece]; ok {
-				retIDs = append(retIDs, id)
-				retTokens = append(retTokens, piece)
+		if nextSpecial == nil {
+			return n
+		}
 
-				continue
-			}
+		n++
+		start = nextSpecial[1]
+	}
+}
 
-			ids, tokens := bytePairEncode([]byte(piece), bpe.encoder)
-			retIDs = append(retIDs, ids...)
-			retTokens = append(retTokens, tokens...)
-		}
+// CountOrdinary returns the number of tokens EncodeOrdinary would produce for the input text
+// without building token IDs or token strings.
+func (bpe *coreBPE) CountOrdinary(text string) int {
+	var scratch []mergePart
+
+	return bpe.countOrdinary(text, &scratch)
+}
+
+// countOrdinary counts the tokens of text treating all tokens as ordinary tokens. The parts of
+// merged pieces are kept in scratch, which is reused across pieces.
+func (bpe *coreBPE) countOrdinary(text string, scratch *[]mergePart) int {
+	n := 0
+
+	bpe.preTokenizer.split(text, func(start, end int) {
+		n += bpe.countPiece(text[start:end], scratch)
+	})
+
+	return n
+}
+
+// countPiece returns the number of tokens of the pre-tokenized piece.
+func (bpe *coreBPE) countPiece(piece string, scratch *[]mergePart) int {
+	if _, ok := bpe.encoder[piece]; ok || len(piece) == 1 {
+		return 1
+	}
+
+	if len(piece) >= heapMergeThreshold {
+		return len(bytePairMergeHeap(piece, bpe.encoder)) - 1
+	}
+
+	*scratch = bytePairMergeParts(piece, bpe.encoder, (*scratch)[:0])
+
+	return len(*scratch) - 1
+}
+
+// encode splits text into ordinary and allowed special tokens and calls fn for every token.
+// All positions used while splitting the text are byte offsets into text.
+func (bpe *coreBPE) encode(text string, allowedSpecial map[string]any, fn tokenFunc) {
+	var scratch []mergePart
+
+	start := 0
+
+	for {
+		nextSpecial := bpe.findNextAllowedSpecial(text, start, allowedSpecial)
 
+		end := len(text)
 		if nextSpecial != nil {
-			temp := cut(textRunes, start+nextSpecial[0], start+nextSpecial[1])
-			id := bpe.specialTokensEncoder[temp]
-			retIDs = append(retIDs, id)
-			retTokens = append(retTokens, temp)
-			start = start + nextSpecial[1]
-		} else {
-			break
+			end = nextSpecial[0]
+		}
+
+		bpe.encodeOrdinary(text, start, end, &scratch, fn)
+
+		if nextSpecial == nil {
+			return
 		}
+
+		fn(bpe.specialTokensEncoder[text[nextSpecial[0]:nextSpecial[1]]], nextSpecial[0], nextSpecial[1])
+
+		start = nextSpecial[1]
 	}
+}
 
-	return retIDs, retTokens
+// encodeOrdinary encodes text[start:end] treating all tokens as ordinary tokens and calls fn for every token.
+// The reported token positions are byte offsets into text. The parts of merged pieces are kept in
+// scratch, which is reused across pieces.
+func (bpe *coreBPE) encodeOrdinary(text string, start, end int, scratch *[]mergePart, fn tokenFunc) {
+	bpe.preTokenizer.split(text[start:end], func(pieceStart, pieceEnd int) {
+		bpe.encodePiece(text, start+pieceStart, start+pieceEnd, scratch, fn)
+	})
 }
 
-// EncodeOrdinary performs tokenization and encoding of the input text using the Byte Pair Encoding (BPE) algorithm,
-// treating all tokens as ordinary tokens (not special tokens).
-// It takes the input text as a parameter and returns the encoded token IDs and corresponding tokens as slices.
-func (bpe *coreBPE) EncodeOrdinary(text string) ([]uint, []string) {
-	retIDs := []uint{}
-	retTokens := []string{}
-	textRunes := []rune(text)
+// encodePiece encodes the pre-tokenized piece text[start:end] and calls fn for every token.
+func (bpe *coreBPE) encodePiece(text string, start, end int, scratch *[]mergePart, fn tokenFunc) {
+	piece := text[start:end]
+	if id, ok := bpe.encoder[piece]; ok {
+		fn(id, start, end)
+		return
+	}
+
+	// Long pieces are merged with bytePairMergeHeap to avoid quadratic run time.
+	if len(piece) >= heapMergeThreshold {
+		bounds := bytePairMergeHeap(piece, bpe.encoder)
+		for i := 0; i < len(bounds)-1; i++ {
+			fn(bpe.encoder[piece[bounds[i]:bounds[i+1]]], start+bounds[i], start+bounds[i+1])
+		}
+
+		return
+	}
+
+	*scratch = bytePairMergeParts(piece, bpe.encoder, (*scratch)[:0])
+
+	parts := *scratch
+	for i := 0; i < len(parts)-1; i++ {
+		fn(bpe.encoder[piece[parts[i].start:parts[i+1].start]], start+parts[i].start, start+parts[i+1].start)
+	}
+}
 
-	for _, mat := range findRegex2AllStringMatchIndex(text, bpe.tlRegex) {
-		piece := cut(textRunes, mat[0], mat[1])
-		if id, ok := bpe.encoder[piece]; ok {
-			retIDs = append(retIDs, id)
-			retTokens = append(retTokens, piece)
+// findNextAllowedSpecial returns the byte range [start, end] of the first allowed special token
+// in text at or after the byte offset start, or nil if there is none.
+func (bpe *coreBPE) findNextAllowedSpecial(text string, start int, allowedSpecial map[string]any) []int {
+	if bpe.tlSpecialRegex == nil || len(allowedSpecial) == 0 {
+		return nil
+	}
 
-			continue
+	for start < len(text) {
+		m := findRegex2StringIndex(text[start:], bpe.tlSpecialRegex)
+		if m == nil || m[1] == 0 {
+			return nil
 		}
 
-		ids, tokens := bytePairEncode([]byte(piece), bpe.encoder)
-		retIDs = append(retIDs, ids...)
-		retTokens = append(retTokens, tokens...)
+		if _, ok := allowedSpecial[text[start+m[0]:start+m[1]]]; ok {
+			return []int{start + m[0], start + m[1]}
+		}
+
+		start += m[1]
 	}
 
-	return retIDs, retTokens
+	return nil
 }
 
 // Decode performs decoding of the input token IDs and reconstructs the original text.
@@ -175,12 +299,7 @@ func (bpe *coreBPE) Decode(tokens []uint) []byte {
 	ret := make([]byte, 0, len(tokens)*2)
 
 	for _, token := range tokens {
-		tokenBytes, ok := bpe.decoder[token]
-		if !ok {
-			tokenBytes = bpe.specialTokensDecoder[token]
-		}
-
-		if len(tokenBytes) > 0 {
+		if tokenBytes, ok := bpe.tokenBytes(token); ok {
 			ret = append(ret, tokenBytes...)
 		}
 	}
@@ -188,26 +307,52 @@ func (bpe *coreBPE) Decode(tokens []uint) []byte {
 	return ret
 }
 
+// tokenBytes returns the bytes of the ordinary or special token with the given ID.
+func (bpe *coreBPE) tokenBytes(token uint) (string, bool) {
+	if tokenBytes, ok := bpe.decoder[token]; ok {
+		return tokenBytes, true
+	}
+
+	tokenBytes, ok := bpe.specialTokensDecoder[token]
+
+	return tokenBytes, ok
+}
+
+// mergePart is a part of a piece during byte pair merging. It holds the start offset of the part
+// and the rank of merging it with its successor.
+type mergePart struct {
+	start int
+	rank  uint
+}
+
 // bytePairMerge performs the byte pair merging process on the given piece using the provided ranks.
-// It returns the merged IDs and tokens. The ranks map should contain precomputed ranks for each token.
-func bytePairMerge(piece []byte, ranks map[string]uint) ([]uint, []string) {
-	// Structure to store the start index and rank of each part
-	type part struct {
-		start int
-		rank  uint
+// It returns the boundaries of the merged tokens as byte offsets into piece, starting with 0 and
+// ending with len(piece). The ranks map should contain precomputed ranks for each token.
+func bytePairMerge(piece string, ranks map[string]uint) []int {
+	parts := bytePairMergeParts(piece, ranks, nil)
+
+	bounds := make([]int, len(parts))
+	for i, p := range parts {
+		bounds[i] = p.start
 	}
 
+	return bounds
+}
+
+// bytePairMergeParts performs the merges of bytePairMerge and returns the merged parts followed by
+// a part starting at len(piece). The parts are appended to buf, which allows reusing its capacity.
+func bytePairMergeParts(piece string, ranks map[string]uint, buf []mergePart) []mergePart {
 	// Create initial parts with start index and maximum rank
-	parts := make([]part, len(piece)+1)
-	for i := 0; i < len(parts); i++ {
-		parts[i] = part{i, math.MaxUint}
+	parts := buf[:0]
+	for i := 0; i <= len(piece); i++ {
+		parts = append(parts, mergePart{i, math.MaxUint})
 	}
 
 	// Function to get the rank of a given part
 	getRank := func(idx, skip int) uint {
 		if idx+skip+2 < len(parts) {
 			p := piece[parts[idx].start:parts[idx+skip+2].start]
-			if rank, ok := ranks[string(p)]; ok {
+			if rank, ok := ranks[p]; ok {
 				return rank
 			}
 		}
@@ -243,31 +388,11 @@ func bytePairMerge(piece []byte, ranks map[string]uint) ([]uint, []string) {
 		parts = append(parts[:minIdx+1], parts[minIdx+2:]...)
 	}
 
-	ids := make([]uint, len(parts)-1)
-	tokens := make([]string, len(parts)-1)
-
-	for i := 0; i < len(ids); i++ {
-		token := string(piece[parts[i].start:parts[i+1].start])
-		tokens[i] = token
-		ids[i] = ranks[token]
-	}
-
-	return ids, tokens
-}
-
-// bytePairEncode encodes the given piece using byte pair encoding with the provided ranks.
-// It returns the encoded IDs and tokens. The ranks map should contain precomputed ranks for each token.
-func bytePairEncode(piece []byte, ranks map[string]uint) ([]uint, []string) {
-	if len(piece) == 1 {
-		v := ranks[string(piece)]
-		return []uint{v}, []string{string(piece)}
-	}
-
-	return bytePairMerge(piece, ranks)
+	return parts
 }
 
 // findRegex2StringIndex finds the index range of the first occurrence of the regular expression pattern in the given text.
-// It returns the index range as a slice [start, end] if a match is found, or nil if no match is found.
+// It returns the byte index range as a slice [start, end] if a match is found, or nil if no match is found.
 // The function takes the input text as a string and the regular expression pattern as a compiled *regexp2.Regexp.
 func findRegex2StringIndex(text string, reg *regexp2.Regexp) []int {
 	m, _ := reg.FindStringMatch(text)
@@ -276,41 +401,51 @@ func findRegex2StringIndex(text string, reg *regexp2.Regexp) []int {
 		return nil
 	}
 
-	// Extract the index range of the match and return it as [start, end].
-	result := make([]int, 2)
-	result[0] = m.Index
-	result[1] = m.Index + m.Length
+	// regexp2 reports rune indices, convert them to byte offsets.
+	start := advanceRunes(text, 0, m.Index)
+	end := advanceRunes(text, start, m.Length)
 
-	return result
+	return []int{start, end}
 }
 
 // findRegex2AllStringMatchIndex finds all index ranges of the occurrences of the regular expression pattern in the given text.
-// It returns a slice of index ranges, where each range is represented as a slice [start, end].
+// It returns a slice of byte index ranges, where each range is represented as a slice [start, end].
 // The function takes the input text as a string and the regular expression pattern as a compiled *regexp2.Regexp.
 func findRegex2AllStringMatchIndex(text string, reg *regexp2.Regexp) [][]int {
 	var matches [][]int
 
-	m, _ := reg.FindStringMatch(text)
-	for m != nil {
-		// Extract the index range of the match and append it to the matches slice.
-		result := make([]int, 2)
-		result[0] = m.Index
-		result[1] = m.Index + m.Length
-		matches = append(matches, result)
-		m, _ = reg.FindNextMatch(m)
-	}
+	forEachRegex2Match(text, reg, func(start, end int) {
+		matches = append(matches, []int{start, end})
+	})
 
 	return matches
 }
 
-func cut(runes []rune, start, end int) string {
-	if start < 0 {
-		start = 0
+// forEachRegex2Match calls fn with the byte index range of every occurrence of the regular expression
+// pattern in the given text, in order.
+func forEachRegex2Match(text string, reg *regexp2.Regexp, fn func(start, end int)) {
+	// pos is the byte offset of the rune with index runeIdx
+	pos, runeIdx := 0, 0
+
+	m, _ := reg.FindStringMatch(text)
+	for m != nil {
+		// regexp2 reports rune indices, convert them to byte offsets.
+		start := advanceRunes(text, pos, m.Index-runeIdx)
+		end := advanceRunes(text, start, m.Length)
+		fn(start, end)
+
+		pos, runeIdx = end, m.Index+m.Length
+		m, _ = reg.FindNextMatch(m)
 	}
+}
 
-	if end > len(runes) {
-		end = len(runes)
+// advanceRunes returns the byte offset reached by skipping n runes in text, starting at byte offset pos.
+// Invalid UTF-8 bytes count as one rune each, which matches how regexp2 converts strings to runes.
+func advanceRunes(text string, pos, n int) int {
+	for ; n > 0 && pos < len(text); n-- {
+		_, size := utf8.DecodeRuneInString(text[pos:])
+		pos += size
 	}
 
-	return string(runes[start:end])
+	return pos
 }
@@ -0,0 +1,150 @@
+package tiktoken
+
+import (
+	"math"
+)
+
+// heapMergeThreshold is the piece length in bytes from which pieces are merged with
+// bytePairMergeHeap instead of the quadratic bytePairMerge. Below it the linear scan is faster.
+const heapMergeThreshold = 64
+
+// mergeCandidate is a possible merge of the part starting at start with its right neighbour.
+type mergeCandidate struct {
+	rank  uint
+	start int
+}
+
+// less orders candidates by rank and, for equal ranks, by position, so that the leftmost merge wins.
+func (c mergeCandidate) less(other mergeCandidate) bool {
+	if c.rank != other.rank {
+		return c.rank < other.rank
+	}
+
+	return c.start < other.start
+}
+
+// mergeQueue is a binary min-heap of merge candidates.
+type mergeQueue []mergeCandidate
+
+func (q *mergeQueue) push(c mergeCandidate) {
+	*q = append(*q, c)
+
+	h := *q
+	for i := len(h) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if !h[i].less(h[parent]) {
+			break
+		}
+
+		h[i], h[parent] = h[parent], h[i]
+		i = parent
+	}
+}
+
+func (q *mergeQueue) pop() mergeCandidate {
+	h := *q
+	top := h[0]
+	last := len(h) - 1
+	h[0] = h[last]
+	h = h[:last]
+
+	for i := 0; ; {
+		smallest := i
+		left, right := 2*i+1, 2*i+2
+
+		if left < len(h) && h[left].less(h[smallest]) {
+			smallest = left
+		}
+
+		if right < len(h) && h[right].less(h[smallest]) {
+			smallest = right
+		}
+
+		if smallest == i {
+			break
+		}
+
+		h[i], h[smallest] = h[smallest], h[i]
+		i = smallest
+	}
+
+	*q = h
+
+	return top
+}
+
+// bytePairMergeHeap performs the same merges as bytePairMerge in O(n log n) by keeping the parts
+// in a linked list and the candidate merges in a priority queue. Outdated candidates are skipped
+// when they are popped. It returns the boundaries of the merged tokens as byte offsets into piece.
+func bytePairMergeHeap(piece string, ranks map[string]uint) []int {
+	n := len(piece)
+
+	// Every part is identified by its start offset. next[i] is the start of the part following
+	// the part starting at i (n for the last part), prev[i] the start of the preceding part
+	// (-1 for the first part). rank[i] is the rank of merging part i with its successor.
+	next := make([]int, n)
+	prev := make([]int, n)
+	rank := make([]uint, n)
+	removed := make([]bool, n)
+
+	getRank := func(start int) uint {
+		if next[start] >= n {
+			return math.MaxUint
+		}
+
+		if r, ok := ranks[piece[start:next[next[start]]]]; ok {
+			return r
+		}
+
+		return math.MaxUint
+	}
+
+	for i := 0; i < n; i++ {
+		next[i] = i + 1
+		prev[i] = i - 1
+	}
+
+	queue := make(mergeQueue, 0, n)
+
+	for i := 0; i < n; i++ {
+		rank[i] = getRank(i)
+		if rank[i] != math.MaxUint {
+			queue.push(mergeCandidate{rank: rank[i], start: i})
+		}
+	}
+
+	for len(queue) > 0 {
+		c := queue.pop()
+		if removed[c.start] || rank[c.start] != c.rank {
+			continue
+		}
+
+		// merge the part with its successor
+		right := next[c.start]
+		removed[right] = true
+		next[c.start] = next[right]
+
+		if next[c.start] < n {
+			prev[next[c.start]] = c.start
+		}
+
+		rank[c.start] = getRank(c.start)
+		if rank[c.start] != math.MaxUint {
+			queue.push(mergeCandidate{rank: rank[c.start], start: c.start})
+		}
+
+		if p := prev[c.start]; p >= 0 {
+			rank[p] = getRank(p)
+			if rank[p] != math.MaxUint {
+				queue.push(mergeCandidate{rank: rank[p], start: p})
+			}
+		}
+	}
+
+	bounds := make([]int, 0, n+1)
+	for i := 0; i < n; i = next[i] {
+		bounds = append(bounds, i)
+	}
+
+	return append(bounds, n)
+}
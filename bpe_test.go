@@ -0,0 +1,183 @@
+//go:build !tiktoken_minimal
+
+package tiktoken
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+
+	"github.com/dlclark/regexp2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// textWithSpecials is a random UTF-8 string mixing ordinary text with special tokens.
+type textWithSpecials string
+
+var textFragments = []string{
+	"hello", " world", "Hello", "  ", "\n", "\r\n", "\t", "123", "4567", "'s", "'LL", "!?", "...",
+	"é", "ü", "ß", "你好", "世界", "！", "🙂", "👩‍💻", "ϰ", "™", "́", " ", "　",
+	EndOfText, FimPrefix, FimMiddle, FimSuffix, EndOfPrompt, "<|", "|>", "<|endof", "text|>",
+}
+
+// Generate implements quick.Generator.
+func (textWithSpecials) Generate(r *rand.Rand, size int) reflect.Value {
+	var sb strings.Builder
+
+	for i := r.Intn(size + 1); i > 0; i-- {
+		if r.Intn(4) == 0 {
+			// arbitrary valid rune
+			sb.WriteRune(rune(r.Intn(utf8.MaxRune + 1)))
+			continue
+		}
+
+		sb.WriteString(textFragments[r.Intn(len(textFragments))])
+	}
+
+	return reflect.ValueOf(textWithSpecials(sb.String()))
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	for _, name := range []string{CL100kBase, O200kBase, R50kBase} {
+		name := name
+
+		t.Run(name, func(t *testing.T) {
+			encoding, err := NewEncodingByName(name)
+			require.NoError(t, err)
+
+			allSpecial := func(text textWithSpecials) bool {
+				ids, tokens, err := encoding.Encode(string(text), WithAllSpecialAllowed())
+				if err != nil {
+					return false
+				}
+
+				return string(encoding.Decode(ids)) == string(text) && strings.Join(tokens, "") == string(text)
+			}
+
+			ordinary := func(text textWithSpecials) bool {
+				ids, tokens := encoding.EncodeOrdinary(string(text))
+
+				return string(encoding.Decode(ids)) == string(text) && strings.Join(tokens, "") == string(text)
+			}
+
+			someSpecial := func(text textWithSpecials) bool {
+				ids, _, err := encoding.Encode(string(text), WithAllowedSpecial(EndOfText), WithSpecialAsText())
+				if err != nil {
+					return false
+				}
+
+				return string(encoding.Decode(ids)) == string(text)
+			}
+
+			config := &quick.Config{MaxCount: 500}
+
+			assert.NoError(t, quick.Check(allSpecial, config))
+			assert.NoError(t, quick.Check(ordinary, config))
+			assert.NoError(t, quick.Check(someSpecial, config))
+		})
+	}
+}
+
+func TestEncodeSpecialAfterMultiByteText(t *testing.T) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	require.NoError(t, err)
+
+	text := "你好世界！<|endoftext|>héllo"
+
+	ids, tokens, err := encoding.Encode(text, WithAllSpecialAllowed())
+	require.NoError(t, err)
+
+	assert.Equal(t, []uint{57668, 53901, 3574, 244, 98220, 6447, 100257, 71, 19010, 385}, ids)
+	assert.Equal(t, text, strings.Join(tokens, ""))
+	assert.Equal(t, text, string(encoding.Decode(ids)))
+}
+
+func TestFindRegex2AllStringMatchIndex(t *testing.T) {
+	text := "héllo 世界\xff ok"
+
+	var pieces []string
+	for _, m := range findRegex2AllStringMatchIndex(text, regexp2.MustCompile(cl100kPatStr, regexp2.None)) {
+		pieces = append(pieces, text[m[0]:m[1]])
+	}
+
+	assert.Equal(t, []string{"héllo", " 世界", "\xff", " ok"}, pieces)
+}
+
+func FuzzBytePairMergeHeap(f *testing.F) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	require.NoError(f, err)
+
+	ranks := encoding.coreBPE.encoder
+
+	f.Add("hello world")
+	f.Add(strings.Repeat(" ", 300))
+	f.Add(strings.Repeat("a", 257))
+	f.Add(strings.Repeat("ab", 100) + "你好世界")
+	f.Add("aGVsbG8gd29ybGQhIFRoaXMgaXMgYSBiYXNlNjQgYmxvYg==")
+	f.Add("\xff\xfe\x00\x01")
+
+	f.Fuzz(func(t *testing.T, piece string) {
+		if len(piece) < 2 {
+			return
+		}
+
+		assert.Equal(t, bytePairMerge(piece, ranks), bytePairMergeHeap(piece, ranks))
+	})
+}
+
+func BenchmarkBytePairMerge(b *testing.B) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	require.NoError(b, err)
+
+	ranks := encoding.coreBPE.encoder
+
+	inputs := map[string]string{
+		"whitespace": strings.Repeat(" ", 10000),
+		"repeated":   strings.Repeat("a", 10000),
+		"base64":     strings.Repeat("aGVsbG8gd29ybGQhIFRoaXMgaXMgYSBiYXNlNjQgYmxvYg", 200),
+	}
+
+	for _, name := range []string{"whitespace", "repeated", "base64"} {
+		piece := inputs[name]
+
+		b.Run(name+"/linear", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bytePairMerge(piece, ranks)
+			}
+		})
+
+		b.Run(name+"/heap", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bytePairMergeHeap(piece, ranks)
+			}
+		})
+	}
+}
+
+func BenchmarkBytePairMergeShort(b *testing.B) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	require.NoError(b, err)
+
+	ranks := encoding.coreBPE.encoder
+
+	for _, n := range []int{16, 64, 128, 256} {
+		piece := strings.Repeat("xq", n/2)
+
+		b.Run(fmt.Sprintf("%d/linear", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bytePairMerge(piece, ranks)
+			}
+		})
+
+		b.Run(fmt.Sprintf("%d/heap", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bytePairMergeHeap(piece, ranks)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package tiktoken
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Byte-level BPE vocabularies such as the GPT-2 encoder.json and Hugging Face tokenizer.json
+// files store tokens as text in which every byte is replaced by a printable rune. Printable
+// Latin-1 bytes except the space map to themselves, all other bytes map to runes from U+0100
+// in byte order.
+var (
+	// byteLevelOrder lists the bytes in the order of their single byte token ranks.
+	byteLevelOrder [256]byte
+	// byteLevelRunes maps every byte to its rune.
+	byteLevelRunes [256]rune
+	// byteLevelBytes maps the runes back to their bytes.
+	byteLevelBytes = make(map[rune]byte, 256)
+)
+
+func init() {
+	n := 0
+
+	for b := 0; b < 256; b++ {
+		if isByteLevelPrintable(byte(b)) {
+			byteLevelOrder[n] = byte(b)
+			byteLevelRunes[b] = rune(b)
+			n++
+		}
+	}
+
+	shifted := 0
+
+	for b := 0; b < 256; b++ {
+		if !isByteLevelPrintable(byte(b)) {
+			byteLevelOrder[n] = byte(b)
+			byteLevelRunes[b] = rune(256 + shifted)
+			n++
+			shifted++
+		}
+	}
+
+	for b, r := range byteLevelRunes {
+		byteLevelBytes[r] = byte(b)
+	}
+}
+
+// isByteLevelPrintable reports whether the byte is represented by itself in byte-level vocabularies.
+func isByteLevelPrintable(b byte) bool {
+	return ('!' <= b && b <= '~') || ('¡' <= b && b <= '¬') || ('®' <= b && b <= 'ÿ')
+}
+
+// decodeByteLevel converts a byte-level token back to its bytes.
+func decodeByteLevel(token string) (string, error) {
+	var sb strings.Builder
+
+	sb.Grow(len(token))
+
+	for _, r := range token {
+		b, ok := byteLevelBytes[r]
+		if !ok {
+			return "", fmt.Errorf("invalid byte-level token %q", token)
+		}
+
+		sb.WriteByte(b)
+	}
+
+	return sb.String(), nil
+}
+
+// encodeByteLevel converts the bytes of a token to their byte-level representation.
+func encodeByteLevel(token string) string {
+	var sb strings.Builder
+
+	sb.Grow(2 * len(token))
+
+	for i := 0; i < len(token); i++ {
+		sb.WriteRune(byteLevelRunes[token[i]])
+	}
+
+	return sb.String()
+}
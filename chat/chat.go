@@ -0,0 +1,148 @@
+// Package chat counts the prompt tokens of chat completion requests for OpenAI chat models.
+package chat
+
+import (
+	"fmt"
+
+	"github.com/hupe1980/go-tiktoken"
+)
+
+// Message roles.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Message is a single message of a chat completion request.
+type Message struct {
+	Role    string
+	Name    string
+	Content string
+	// Parts holds the content of multimodal messages, in addition to Content.
+	Parts []ContentPart
+}
+
+// ContentPart is a part of the content of a multimodal message, either a text or an image.
+type ContentPart struct {
+	Text  string
+	Image *Image
+}
+
+// replyPrimingTokens is the number of tokens every reply is primed with (<|start|>assistant<|message|>).
+const replyPrimingTokens = 3
+
+// overhead holds the number of tokens a chat model adds to the tokens of the message fields.
+type overhead struct {
+	tokensPerMessage int
+	tokensPerName    int
+}
+
+// defaultOverhead is the message overhead of the OpenAI chat models.
+var defaultOverhead = overhead{tokensPerMessage: 3, tokensPerName: 1}
+
+// chatOverheads maps the models whose message overhead differs from defaultOverhead to their overheads.
+var chatOverheads = map[string]overhead{
+	"gpt-3.5-turbo-0301": {tokensPerMessage: 4, tokensPerName: -1}, // <|start|>{role/name}\n{content}<|end|>\n, the name replaces the role
+}
+
+// Counter counts the prompt tokens of chat completion requests for a model.
+// A Counter is safe for concurrent use by multiple goroutines.
+type Counter struct {
+	model    string
+	encoding *tiktoken.Encoding
+	info     tiktoken.ModelInfo
+	overhead overhead
+}
+
+// NewCounter returns a Counter for the given chat model. The model is looked up in the tiktoken
+// model registry, see tiktoken.LookupModel, and must be an OpenAI chat model.
+func NewCounter(model string) (*Counter, error) {
+	info, ok := tiktoken.LookupModel(model)
+	if !ok || !info.Chat || (info.Encoding != tiktoken.CL100kBase && info.Encoding != tiktoken.O200kBase) {
+		return nil, fmt.Errorf("chat token counting not implemented for model %s", model)
+	}
+
+	encoding, err := tiktoken.NewEncodingByName(info.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	o, ok := chatOverheads[model]
+	if !ok {
+		o = defaultOverhead
+	}
+
+	return &Counter{
+		model:    model,
+		encoding: encoding,
+		info:     info,
+		overhead: o,
+	}, nil
+}
+
+// Model returns the model of the Counter.
+func (c *Counter) Model() string {
+	return c.model
+}
+
+// Encoding returns the Encoding of the Counter's model.
+func (c *Counter) Encoding() *tiktoken.Encoding {
+	return c.encoding
+}
+
+// CountMessages returns the number of prompt tokens of a request with the given messages,
+// including the tokens priming the assistant's reply and the images of multimodal messages.
+// Special tokens in the message fields are counted as ordinary text, as the API does.
+// It returns an error if a message contains an image the model cannot price.
+func (c *Counter) CountMessages(messages []Message) (int, error) {
+	n := replyPrimingTokens
+
+	for _, m := range messages {
+		tokens, err := c.countMessage(m)
+		if err != nil {
+			return 0, err
+		}
+
+		n += tokens
+	}
+
+	return n, nil
+}
+
+// countMessage returns the number of tokens of a single message.
+func (c *Counter) countMessage(m Message) (int, error) {
+	n := c.overhead.tokensPerMessage
+	n += c.encoding.CountOrdinary(m.Role)
+	n += c.encoding.CountOrdinary(m.Content)
+
+	if m.Name != "" {
+		n += c.encoding.CountOrdinary(m.Name) + c.overhead.tokensPerName
+	}
+
+	for _, part := range m.Parts {
+		n += c.encoding.CountOrdinary(part.Text)
+
+		if part.Image != nil {
+			tokens, err := c.CountImage(*part.Image)
+			if err != nil {
+				return 0, err
+			}
+
+			n += tokens
+		}
+	}
+
+	return n, nil
+}
+
+// CountMessages returns the number of prompt tokens of a request with the given messages for the model.
+func CountMessages(model string, messages []Message) (int, error) {
+	c, err := NewCounter(model)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.CountMessages(messages)
+}
@@ -0,0 +1,75 @@
+//go:build !tiktoken_minimal
+
+package chat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exampleMessages are the messages of the token counting example of the OpenAI cookbook.
+var exampleMessages = []Message{
+	{Role: RoleSystem, Content: "You are a helpful, pattern-following assistant that translates corporate jargon into plain English."},
+	{Role: RoleSystem, Name: "example_user", Content: "New synergies will help drive top-line growth."},
+	{Role: RoleSystem, Name: "example_assistant", Content: "Things working well together will increase revenue."},
+	{Role: RoleSystem, Name: "example_user", Content: "Let's circle back when we have more bandwidth to touch base on opportunities for increased leverage."},
+	{Role: RoleSystem, Name: "example_assistant", Content: "Let's talk later when we're less busy about how to do better."},
+	{Role: RoleUser, Content: "This late pivot means we don't have time to boil the ocean for the client deliverable."},
+}
+
+func TestCountMessages(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected int
+	}{
+		{model: "gpt-3.5-turbo-0301", expected: 127},
+		{model: "gpt-3.5-turbo-0613", expected: 129},
+		{model: "gpt-3.5-turbo", expected: 129},
+		{model: "gpt-4-0314", expected: 129},
+		{model: "gpt-4-0613", expected: 129},
+		{model: "gpt-4", expected: 129},
+		{model: "gpt-4o", expected: 124},
+		{model: "gpt-4o-mini", expected: 124},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.model, func(t *testing.T) {
+			n, err := CountMessages(tt.model, exampleMessages)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, n)
+		})
+	}
+}
+
+func TestNewCounter(t *testing.T) {
+	t.Run("empty request", func(t *testing.T) {
+		c, err := NewCounter("gpt-4o-2024-05-13")
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4o-2024-05-13", c.Model())
+		assert.Equal(t, "o200k_base", c.Encoding().Name())
+
+		n, err := c.CountMessages(nil)
+		require.NoError(t, err)
+		assert.Equal(t, replyPrimingTokens, n)
+	})
+
+	t.Run("special tokens as text", func(t *testing.T) {
+		c, err := NewCounter("gpt-4")
+		require.NoError(t, err)
+
+		n, err := c.CountMessages([]Message{{Role: RoleUser, Content: "<|endoftext|>"}})
+		require.NoError(t, err)
+		assert.Greater(t, n, 3+1+1+1)
+	})
+
+	t.Run("unsupported model", func(t *testing.T) {
+		for _, model := range []string{"text-davinci-003", "gpt-3.5-turbo-instruct-0914", "claude-2.1", "unknown-model"} {
+			_, err := NewCounter(model)
+			assert.EqualError(t, err, "chat token counting not implemented for model "+model)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package chat
+
+import "fmt"
+
+// Image detail levels.
+const (
+	DetailAuto = "auto"
+	DetailLow  = "low"
+	DetailHigh = "high"
+)
+
+// Image describes an image input of a multimodal message.
+type Image struct {
+	Width  int
+	Height int
+	// Detail is one of DetailAuto, DetailLow or DetailHigh. The empty string is equivalent to
+	// DetailAuto, which is priced like DetailHigh as an upper bound.
+	Detail string
+}
+
+const (
+	imageMaxSide      = 2048 // high detail images are scaled to fit into a square of this size
+	imageMaxShortSide = 768  // and then scaled down until their shortest side has at most this size
+	imageTileSize     = 512
+)
+
+// CountImage returns the number of tokens of the given image for the Counter's model.
+// High detail images are scaled to fit into 2048x2048 and then down to a shortest side of 768px,
+// and cost the ImageBaseTokens of the model plus ImageTileTokens per 512px tile.
+func (c *Counter) CountImage(img Image) (int, error) {
+	if c.info.ImageBaseTokens == 0 {
+		return 0, fmt.Errorf("model %s does not accept images", c.model)
+	}
+
+	detail := img.Detail
+	if detail == "" {
+		detail = DetailAuto
+	}
+
+	switch detail {
+	case DetailLow:
+		return c.info.ImageBaseTokens, nil
+	case DetailHigh, DetailAuto:
+	default:
+		return 0, fmt.Errorf("unsupported image detail: %s", img.Detail)
+	}
+
+	if img.Width <= 0 || img.Height <= 0 {
+		return 0, fmt.Errorf("invalid image size: %dx%d", img.Width, img.Height)
+	}
+
+	w, h := scaleImage(img.Width, img.Height)
+	if w < 1 {
+		w = 1
+	}
+
+	if h < 1 {
+		h = 1
+	}
+
+	tiles := ceilDiv(w, imageTileSize) * ceilDiv(h, imageTileSize)
+
+	return c.info.ImageBaseTokens + tiles*c.info.ImageTileTokens, nil
+}
+
+// CountImage returns the number of tokens of the given image for the model.
+func CountImage(model string, img Image) (int, error) {
+	c, err := NewCounter(model)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.CountImage(img)
+}
+
+// scaleImage returns the size a high detail image is scaled to before it is tiled.
+func scaleImage(w, h int) (int, int) {
+	if w > imageMaxSide || h > imageMaxSide {
+		if w >= h {
+			w, h = imageMaxSide, h*imageMaxSide/w
+		} else {
+			w, h = w*imageMaxSide/h, imageMaxSide
+		}
+	}
+
+	if w <= h && w > imageMaxShortSide {
+		w, h = imageMaxShortSide, h*imageMaxShortSide/w
+	} else if h < w && h > imageMaxShortSide {
+		w, h = w*imageMaxShortSide/h, imageMaxShortSide
+	}
+
+	return w, h
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
@@ -0,0 +1,94 @@
+//go:build !tiktoken_minimal
+
+package chat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    string
+		image    Image
+		expected int
+	}{
+		{name: "low detail", model: "gpt-4o", image: Image{Width: 4096, Height: 8192, Detail: DetailLow}, expected: 85},
+		{name: "small image", model: "gpt-4o", image: Image{Width: 512, Height: 512, Detail: DetailHigh}, expected: 85 + 170},
+		{name: "shortest side scaled", model: "gpt-4o", image: Image{Width: 1024, Height: 1024, Detail: DetailHigh}, expected: 85 + 4*170},
+		{name: "fit and shortest side scaled", model: "gpt-4o", image: Image{Width: 2048, Height: 4096, Detail: DetailHigh}, expected: 85 + 6*170},
+		{name: "landscape", model: "gpt-4o", image: Image{Width: 1920, Height: 1080}, expected: 85 + 6*170},
+		{name: "not scaled up", model: "gpt-4o", image: Image{Width: 100, Height: 600, Detail: DetailAuto}, expected: 85 + 2*170},
+		{name: "extreme aspect ratio", model: "gpt-4o", image: Image{Width: 10000, Height: 1, Detail: DetailHigh}, expected: 85 + 4*170},
+		{name: "mini", model: "gpt-4o-mini", image: Image{Width: 1024, Height: 1024, Detail: DetailHigh}, expected: 2833 + 4*5667},
+		{name: "mini low detail", model: "gpt-4o-mini-2024-07-18", image: Image{Width: 1024, Height: 1024, Detail: DetailLow}, expected: 2833},
+		{name: "turbo", model: "gpt-4-turbo-2024-04-09", image: Image{Width: 1024, Height: 1024}, expected: 85 + 4*170},
+		{name: "vision preview", model: "gpt-4-vision-preview", image: Image{Width: 512, Height: 512}, expected: 85 + 170},
+		{name: "fine-tuned mini", model: "ft:gpt-4o-mini-2024-07-18:org::id", image: Image{Width: 512, Height: 512}, expected: 2833 + 5667},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := CountImage(tt.model, tt.image)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, n)
+		})
+	}
+}
+
+func TestCountImageErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		model string
+		image Image
+	}{
+		{name: "model without vision", model: "gpt-3.5-turbo", image: Image{Width: 512, Height: 512}},
+		{name: "turbo preview without vision", model: "gpt-4-turbo-preview", image: Image{Width: 512, Height: 512}},
+		{name: "unsupported detail", model: "gpt-4o", image: Image{Width: 512, Height: 512, Detail: "medium"}},
+		{name: "missing size", model: "gpt-4o", image: Image{Detail: DetailHigh}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CountImage(tt.model, tt.image)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCountMultimodalRequest(t *testing.T) {
+	c, err := NewCounter("gpt-4o")
+	require.NoError(t, err)
+
+	text, err := c.CountMessages([]Message{
+		{Role: RoleSystem, Content: "You are a helpful assistant."},
+		{Role: RoleUser, Content: "What is in these images?"},
+	})
+	require.NoError(t, err)
+
+	n, err := c.CountRequest(&Request{Messages: []Message{
+		{Role: RoleSystem, Content: "You are a helpful assistant."},
+		{Role: RoleUser, Parts: []ContentPart{
+			{Text: "What is in these images?"},
+			{Image: &Image{Width: 1024, Height: 1024, Detail: DetailHigh}},
+			{Image: &Image{Width: 1024, Height: 1024, Detail: DetailLow}},
+		}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, text+85+4*170+85, n)
+
+	c, err = NewCounter("gpt-4")
+	require.NoError(t, err)
+
+	_, err = c.CountRequest(&Request{Messages: []Message{
+		{Role: RoleUser, Parts: []ContentPart{{Image: &Image{Width: 512, Height: 512}}}},
+	}})
+	assert.Error(t, err)
+}
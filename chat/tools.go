@@ -0,0 +1,356 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Tool is a tool definition of a chat completion request. Only function tools are supported.
+type Tool struct {
+	Type     string   `json:"type"`
+	Function Function `json:"function"`
+}
+
+// Function is the definition of a function the model may call.
+type Function struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Parameters is the JSON schema of the function parameters. The order of the properties is
+	// preserved, as it is part of the prompt.
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+}
+
+// Tool choice modes.
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
+// ToolChoice controls whether and which tool the model calls. The zero value is equivalent to
+// ToolChoiceAuto.
+type ToolChoice struct {
+	// Mode is one of ToolChoiceAuto, ToolChoiceNone or ToolChoiceRequired.
+	// It is ignored if Function is set.
+	Mode string
+	// Function forces the model to call the function with this name.
+	Function string
+}
+
+// Request is the part of a chat completion request that is part of the prompt.
+type Request struct {
+	Messages   []Message
+	Tools      []Tool
+	ToolChoice ToolChoice
+}
+
+// toolsTokens is the number of tokens the tool definitions add to the prompt besides the definitions.
+const toolsTokens = 9
+
+// CountTools returns the number of tokens the given tool definitions add to a request without a
+// system message. Requests with a system message cost 4 tokens less, see CountRequest.
+func (c *Counter) CountTools(tools []Tool) (int, error) {
+	definitions, err := formatToolDefinitions(tools)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.encoding.CountOrdinary(definitions) + toolsTokens, nil
+}
+
+// CountRequest returns the number of prompt tokens of the given request, including its messages
+// with their images, tool definitions and tool choice. The tool definitions are injected into the
+// system prompt, which changes the cost of the first system message.
+func (c *Counter) CountRequest(req *Request) (int, error) {
+	if len(req.Tools) == 0 {
+		return c.CountMessages(req.Messages)
+	}
+
+	n := replyPrimingTokens
+	padded := false
+
+	for _, m := range req.Messages {
+		if m.Role == RoleSystem && !padded {
+			// the definitions are appended to the first system message, separated by a newline
+			m.Content += "\n"
+			padded = true
+		}
+
+		tokens, err := c.countMessage(m)
+		if err != nil {
+			return 0, err
+		}
+
+		n += tokens
+	}
+
+	toolTokens, err := c.CountTools(req.Tools)
+	if err != nil {
+		return 0, err
+	}
+
+	n += toolTokens
+
+	if padded {
+		n -= 4
+	}
+
+	switch {
+	case req.ToolChoice.Function != "":
+		n += c.encoding.CountOrdinary(req.ToolChoice.Function) + 4
+	case req.ToolChoice.Mode == ToolChoiceNone:
+		n++
+	}
+
+	return n, nil
+}
+
+// formatToolDefinitions renders the tool definitions as the TypeScript namespace the model sees.
+func formatToolDefinitions(tools []Tool) (string, error) {
+	lines := []string{"namespace functions {", ""}
+
+	for _, tool := range tools {
+		if tool.Type != "" && tool.Type != "function" {
+			return "", fmt.Errorf("unsupported tool type: %s", tool.Type)
+		}
+
+		f := tool.Function
+
+		params, err := parseSchema(f.Parameters)
+		if err != nil {
+			return "", fmt.Errorf("invalid parameters of function %s: %w", f.Name, err)
+		}
+
+		if f.Description != "" {
+			lines = append(lines, "// "+f.Description)
+		}
+
+		if len(params.properties) > 0 {
+			props, err := formatObjectProperties(params, 0)
+			if err != nil {
+				return "", fmt.Errorf("invalid parameters of function %s: %w", f.Name, err)
+			}
+
+			lines = append(lines, "type "+f.Name+" = (_: {", props, "}) => any;")
+		} else {
+			lines = append(lines, "type "+f.Name+" = () => any;")
+		}
+
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, "} // namespace functions")
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// formatObjectProperties renders the properties of an object schema, one per line.
+func formatObjectProperties(s *schema, indent int) (string, error) {
+	var lines []string
+
+	for _, p := range s.properties {
+		if p.schema.description != "" && indent < 2 {
+			lines = append(lines, "// "+p.schema.description)
+		}
+
+		typ, err := formatType(p.schema, indent)
+		if err != nil {
+			return "", err
+		}
+
+		if s.isRequired(p.name) {
+			lines = append(lines, p.name+": "+typ+",")
+		} else {
+			lines = append(lines, p.name+"?: "+typ+",")
+		}
+	}
+
+	prefix := strings.Repeat(" ", indent)
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// formatType renders the type of a schema.
+func formatType(s *schema, indent int) (string, error) {
+	switch s.typ {
+	case "string", "number", "integer":
+		if len(s.enum) == 0 {
+			return s.typ, nil
+		}
+
+		values := make([]string, len(s.enum))
+		for i, v := range s.enum {
+			if str, ok := v.(string); ok && s.typ == "string" {
+				values[i] = `"` + str + `"`
+			} else {
+				values[i] = fmt.Sprint(v)
+			}
+		}
+
+		return strings.Join(values, " | "), nil
+	case "array":
+		if s.items == nil {
+			return "any[]", nil
+		}
+
+		typ, err := formatType(s.items, indent)
+		if err != nil {
+			return "", err
+		}
+
+		return typ + "[]", nil
+	case "boolean", "null":
+		return s.typ, nil
+	case "object":
+		props, err := formatObjectProperties(s, indent+2)
+		if err != nil {
+			return "", err
+		}
+
+		return "{\n" + props + "\n}", nil
+	default:
+		return "", fmt.Errorf("unsupported type: %s", s.typ)
+	}
+}
+
+// schema is the subset of a JSON schema that is part of the prompt.
+type schema struct {
+	typ         string
+	description string
+	enum        []any
+	items       *schema
+	properties  []property
+	required    []string
+}
+
+// property is a named property of an object schema.
+type property struct {
+	name   string
+	schema *schema
+}
+
+func (s *schema) isRequired(name string) bool {
+	for _, r := range s.required {
+		if r == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseSchema parses a JSON schema, preserving the order of the object properties.
+// An empty schema is an object without properties.
+func parseSchema(data json.RawMessage) (*schema, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &schema{typ: "object"}, nil
+	}
+
+	var fields struct {
+		Type        json.RawMessage `json:"type"`
+		Description string          `json:"description"`
+		Items       json.RawMessage `json:"items"`
+		Properties  json.RawMessage `json:"properties"`
+		Required    []string        `json:"required"`
+	}
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	s := &schema{
+		description: fields.Description,
+		required:    fields.Required,
+	}
+
+	if len(fields.Type) > 0 {
+		if err := json.Unmarshal(fields.Type, &s.typ); err != nil {
+			return nil, fmt.Errorf("unsupported type: %s", fields.Type)
+		}
+	}
+
+	var enum struct {
+		Enum []any `json:"enum"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(&enum); err != nil {
+		return nil, err
+	}
+
+	s.enum = enum.Enum
+
+	if len(fields.Items) > 0 {
+		items, err := parseSchema(fields.Items)
+		if err != nil {
+			return nil, err
+		}
+
+		s.items = items
+	}
+
+	if len(fields.Properties) > 0 {
+		properties, err := parseProperties(fields.Properties)
+		if err != nil {
+			return nil, err
+		}
+
+		s.properties = properties
+	}
+
+	return s, nil
+}
+
+// parseProperties parses the properties of an object schema in their order of appearance.
+func parseProperties(data json.RawMessage) ([]property, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok == nil {
+		return nil, nil
+	}
+
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, errors.New("properties must be an object")
+	}
+
+	var properties []property
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		name, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid property name: %v", tok)
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+
+		s, err := parseSchema(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		properties = append(properties, property{name: name, schema: s})
+	}
+
+	return properties, nil
+}
@@ -0,0 +1,285 @@
+//go:build !tiktoken_minimal
+
+package chat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFunctionTool(name, description, parameters string) Tool {
+	return Tool{
+		Type: "function",
+		Function: Function{
+			Name:        name,
+			Description: description,
+			Parameters:  json.RawMessage(parameters),
+		},
+	}
+}
+
+func TestFormatToolDefinitions(t *testing.T) {
+	tests := []struct {
+		name     string
+		tools    []Tool
+		expected string
+	}{
+		{
+			name:  "no parameters",
+			tools: []Tool{newFunctionTool("foo", "", `{"type": "object", "properties": {}}`)},
+			expected: `namespace functions {
+
+type foo = () => any;
+
+} // namespace functions`,
+		},
+		{
+			name: "descriptions",
+			tools: []Tool{newFunctionTool("get_weather", "Get the current weather", `{
+				"type": "object",
+				"properties": {
+					"location": {"type": "string", "description": "The city and state, e.g. San Francisco, CA"},
+					"days": {"type": "integer"}
+				},
+				"required": ["location"]
+			}`)},
+			expected: `namespace functions {
+
+// Get the current weather
+type get_weather = (_: {
+// The city and state, e.g. San Francisco, CA
+location: string,
+days?: integer,
+}) => any;
+
+} // namespace functions`,
+		},
+		{
+			name: "enums",
+			tools: []Tool{newFunctionTool("set_mode", "", `{
+				"type": "object",
+				"properties": {
+					"unit": {"type": "string", "enum": ["celsius", "fahrenheit"]},
+					"level": {"type": "number", "enum": [1, 2.5, 10]},
+					"flags": {"type": "array", "items": {"type": "string", "enum": ["a", "b"]}},
+					"any": {"type": "array"}
+				}
+			}`)},
+			expected: `namespace functions {
+
+type set_mode = (_: {
+unit?: "celsius" | "fahrenheit",
+level?: 1 | 2.5 | 10,
+flags?: "a" | "b"[],
+any?: any[],
+}) => any;
+
+} // namespace functions`,
+		},
+		{
+			name: "nested objects",
+			tools: []Tool{newFunctionTool("create_user", "Create a user", `{
+				"type": "object",
+				"properties": {
+					"user": {
+						"type": "object",
+						"description": "The user",
+						"properties": {
+							"name": {"type": "string", "description": "Not rendered below the top level"},
+							"address": {
+								"type": "object",
+								"description": "Not rendered either",
+								"properties": {
+									"street": {"type": "string", "description": "Not rendered at this depth"},
+									"zip": {"type": "integer"}
+								},
+								"required": ["street"]
+							},
+							"tags": {"type": "array", "items": {"type": "object", "properties": {"key": {"type": "string"}}}}
+						},
+						"required": ["name"]
+					},
+					"active": {"type": "boolean"},
+					"deleted_at": {"type": "null"}
+				},
+				"required": ["user"]
+			}`)},
+			expected: `namespace functions {
+
+// Create a user
+type create_user = (_: {
+// The user
+user: {
+  name: string,
+  address?: {
+    street: string,
+    zip?: integer,
+},
+  tags?: {
+    key?: string,
+}[],
+},
+active?: boolean,
+deleted_at?: null,
+}) => any;
+
+} // namespace functions`,
+		},
+		{
+			name: "multiple tools",
+			tools: []Tool{
+				newFunctionTool("foo", "Do a foo", ``),
+				newFunctionTool("bar", "", `{"type": "object", "properties": {"baz": {"type": "string"}}}`),
+			},
+			expected: `namespace functions {
+
+// Do a foo
+type foo = () => any;
+
+type bar = (_: {
+baz?: string,
+}) => any;
+
+} // namespace functions`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			definitions, err := formatToolDefinitions(tt.tools)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, definitions)
+		})
+	}
+}
+
+func TestFormatToolDefinitionsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		tool Tool
+	}{
+		{name: "unsupported tool type", tool: Tool{Type: "code_interpreter"}},
+		{name: "invalid json", tool: newFunctionTool("foo", "", `{`)},
+		{name: "unsupported type", tool: newFunctionTool("foo", "", `{"type": "object", "properties": {"a": {"type": "date"}}}`)},
+		{name: "type union", tool: newFunctionTool("foo", "", `{"type": "object", "properties": {"a": {"type": ["string", "null"]}}}`)},
+		{name: "invalid properties", tool: newFunctionTool("foo", "", `{"type": "object", "properties": []}`)},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := formatToolDefinitions([]Tool{tt.tool})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCountRequest(t *testing.T) {
+	userMessage := []Message{{Role: RoleUser, Content: "hello"}}
+	withSystem := []Message{{Role: RoleSystem, Content: "You are a helpful assistant."}, {Role: RoleUser, Content: "hello"}}
+
+	weather := newFunctionTool("get_weather", "Get the current weather", `{
+		"type": "object",
+		"properties": {
+			"location": {"type": "string", "description": "The city and state, e.g. San Francisco, CA"},
+			"unit": {"type": "string", "enum": ["celsius", "fahrenheit"]}
+		},
+		"required": ["location"]
+	}`)
+
+	tests := []struct {
+		name     string
+		model    string
+		request  Request
+		expected int
+	}{
+		{
+			name:     "no tools",
+			model:    "gpt-3.5-turbo",
+			request:  Request{Messages: userMessage},
+			expected: 8,
+		},
+		{
+			name:     "no parameters",
+			model:    "gpt-3.5-turbo",
+			request:  Request{Messages: userMessage, Tools: []Tool{newFunctionTool("foo", "", `{"type": "object", "properties": {}}`)}},
+			expected: 31,
+		},
+		{
+			name:     "description",
+			model:    "gpt-3.5-turbo",
+			request:  Request{Messages: userMessage, Tools: []Tool{newFunctionTool("foo", "Do a foo", `{"type": "object", "properties": {}}`)}},
+			expected: 36,
+		},
+		{
+			name:  "parameters",
+			model: "gpt-3.5-turbo",
+			request: Request{Messages: userMessage, Tools: []Tool{newFunctionTool("bing_bong", "Do a bing bong", `{
+				"type": "object",
+				"properties": {"foo": {"type": "string"}, "bar": {"type": "number", "description": "A number"}}
+			}`)}},
+			expected: 57,
+		},
+		{
+			name:     "enum",
+			model:    "gpt-4",
+			request:  Request{Messages: userMessage, Tools: []Tool{weather}},
+			expected: 70,
+		},
+		{
+			name:     "system message",
+			model:    "gpt-4",
+			request:  Request{Messages: withSystem, Tools: []Tool{weather}},
+			expected: 76,
+		},
+		{
+			name:     "tool choice none",
+			model:    "gpt-4",
+			request:  Request{Messages: userMessage, Tools: []Tool{weather}, ToolChoice: ToolChoice{Mode: ToolChoiceNone}},
+			expected: 71,
+		},
+		{
+			name:     "tool choice function",
+			model:    "gpt-4",
+			request:  Request{Messages: userMessage, Tools: []Tool{weather}, ToolChoice: ToolChoice{Function: "get_weather"}},
+			expected: 76,
+		},
+		{
+			name:     "o200k",
+			model:    "gpt-4o",
+			request:  Request{Messages: withSystem, Tools: []Tool{weather}, ToolChoice: ToolChoice{Mode: ToolChoiceRequired}},
+			expected: 74,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCounter(tt.model)
+			require.NoError(t, err)
+
+			n, err := c.CountRequest(&tt.request)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, n)
+		})
+	}
+}
+
+func TestCountTools(t *testing.T) {
+	c, err := NewCounter("gpt-4o")
+	require.NoError(t, err)
+
+	n, err := c.CountTools([]Tool{newFunctionTool("foo", "", "")})
+	require.NoError(t, err)
+	assert.Equal(t, c.Encoding().CountOrdinary("namespace functions {\n\ntype foo = () => any;\n\n} // namespace functions")+9, n)
+
+	_, err = c.CountTools([]Tool{{Type: "retrieval"}})
+	assert.Error(t, err)
+}
@@ -1,25 +1,30 @@
+//go:build !tiktoken_minimal || tiktoken_cl100k_base
+
 package tiktoken
 
-import (
-	_ "embed"
-	"strings"
-)
+import _ "embed"
+
+//go:generate go run ./cmd/tiktoken-compile resource/cl100k_base.tiktoken
 
-//go:embed resource/cl100k_base.tiktoken
+//go:embed resource/cl100k_base.tkrb
 var cl100kBase string
 
+func init() {
+	MustRegisterEncoding(CL100kBase, NewCL100kBase)
+}
+
 // NewCL100kBase creates a new Codec instance for the cl100k_base tokenization scheme.
 // It loads the mergeable ranks from the embedded cl100kBase resource.
 // The function returns a pointer to the Codec or an error if any.
 func NewCL100kBase() (*Codec, error) {
-	ranks, err := ConvertToMergeableBPERanks(strings.NewReader(cl100kBase))
+	ranks, err := parseBinaryRanks(cl100kBase)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Codec{
 		Name:           "cl100k_base",
-		PatStr:         `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`,
+		PatStr:         cl100kPatStr,
 		MergeableRanks: ranks,
 		SpecialTokens: map[string]uint{
 			EndOfText:   100257,
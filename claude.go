@@ -1,3 +1,5 @@
+//go:build !tiktoken_minimal || tiktoken_claude
+
 package tiktoken
 
 import (
@@ -12,6 +14,10 @@ import (
 //go:embed resource/claude.json
 var claude string
 
+func init() {
+	MustRegisterEncoding(Claude, NewClaude)
+}
+
 type claudeJSON struct {
 	ExplicitNVocab int             `json:"explicit_n_vocab"`
 	PatStr         string          `json:"pat_str"`
@@ -21,6 +27,7 @@ type claudeJSON struct {
 
 // NewClaude creates a new Codec instance for the claude tokenization scheme.
 // It loads the mergeable ranks from the embedded claude resource.
+// Text is NFKC normalized before encoding.
 // The function returns a pointer to the Codec or an error if any.
 func NewClaude() (*Codec, error) {
 	c := claudeJSON{}
@@ -35,6 +42,10 @@ func NewClaude() (*Codec, error) {
 		return nil, err
 	}
 
+	if offset < 0 {
+		return nil, fmt.Errorf("negative value not allowed: %d", offset)
+	}
+
 	tokens := strings.Split(parts[2], " ")
 
 	mergeableRanks := make(map[string]uint, len(tokens))
@@ -45,24 +56,15 @@ func NewClaude() (*Codec, error) {
 			return nil, bErr
 		}
 
-		if offset < 0 {
-			return nil, fmt.Errorf("negative value not allowed: %d", offset)
-		}
-
-		product := i * offset
-
-		if i < 0 || product < 0 || product > (1<<32-1) {
-			return nil, fmt.Errorf("integer overflow in calculation: %d * %d", i, offset)
-		}
-
-		mergeableRanks[string(t)] = uint(product)
+		mergeableRanks[string(t)] = uint(offset + i)
 	}
 
 	return &Codec{
-		Name:           "claude",
+		Name:           Claude,
 		ExplicitNVocab: c.ExplicitNVocab,
 		PatStr:         c.PatStr,
 		MergeableRanks: mergeableRanks,
 		SpecialTokens:  c.SpecialTokens,
+		Normalization:  NFKC,
 	}, nil
 }
@@ -1,3 +1,5 @@
+//go:build !tiktoken_minimal
+
 package tiktoken
 
 import (
@@ -34,8 +36,50 @@ func TestClaude(t *testing.T) {
 	})
 
 	t.Run("allows special tokens", func(t *testing.T) {
-		idx, _, err := encoding.Encode("<EOT>", AllSpecial, nil)
+		idx, _, err := encoding.Encode("<EOT>", WithAllSpecialAllowed())
 		require.NoError(t, err)
 		require.Equal(t, 1, len(idx))
 	})
 }
+
+func TestClaudeEncoding(t *testing.T) {
+	encoding, err := NewEncodingByName(Claude)
+	require.NoError(t, err)
+
+	t.Run("for model", func(t *testing.T) {
+		enc, err := NewEncodingForModel("claude-2")
+		require.NoError(t, err)
+		assert.Same(t, encoding, enc)
+	})
+
+	t.Run("normalises text", func(t *testing.T) {
+		idx, _ := encoding.EncodeOrdinary("™")
+		assert.Equal(t, 1, len(idx))
+
+		idx, _ = encoding.EncodeOrdinary("ϰ")
+		assert.Equal(t, 1, len(idx))
+	})
+
+	t.Run("ranks do not overlap special tokens", func(t *testing.T) {
+		codec, err := NewClaude()
+		require.NoError(t, err)
+
+		for _, rank := range codec.MergeableRanks {
+			assert.GreaterOrEqual(t, rank, uint(len(codec.SpecialTokens)))
+		}
+	})
+
+	t.Run("token ids", func(t *testing.T) {
+		// ranks start at the offset of the bpe_ranks header and increase by one per token
+		idx, _ := encoding.EncodeOrdinary("hello world!")
+		assert.Equal(t, []uint{9381, 2253, 5}, idx)
+	})
+
+	t.Run("special tokens round trip", func(t *testing.T) {
+		text := "hello world<EOT>"
+		idx, _, err := encoding.Encode(text, WithAllSpecialAllowed())
+		require.NoError(t, err)
+		assert.Equal(t, uint(0), idx[len(idx)-1])
+		assert.Equal(t, text, string(encoding.Decode(idx)))
+	})
+}
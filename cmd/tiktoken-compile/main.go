@@ -0,0 +1,79 @@
+// Command tiktoken-compile converts rank files in the .tiktoken format into the binary rank
+// format, which loads considerably faster with tiktoken.LoadCodecFromFile.
+//
+// Usage:
+//
+//	tiktoken-compile [-o output] input.tiktoken
+//
+// The output defaults to the input file name with the extension replaced by .tkrb.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hupe1980/go-tiktoken"
+)
+
+func main() {
+	output := flag.String("o", "", "output file (default: input with .tkrb extension)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: tiktoken-compile [-o output] input.tiktoken\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	input := flag.Arg(0)
+
+	if *output == "" {
+		*output = strings.TrimSuffix(input, filepath.Ext(input)) + ".tkrb"
+	}
+
+	if err := compile(input, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "tiktoken-compile: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func compile(input, output string) error {
+	in, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	ranks, err := tiktoken.ConvertToMergeableBPERanks(in)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", input, err)
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(out)
+
+	if err := tiktoken.WriteBinaryRanks(w, ranks); err != nil {
+		out.Close()
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
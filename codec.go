@@ -20,6 +20,14 @@ const (
 	EndOfPrompt string = "<|endofprompt|>"
 )
 
+// Constants for unicode normalization forms.
+const (
+	NFC  string = "NFC"
+	NFD  string = "NFD"
+	NFKC string = "NFKC"
+	NFKD string = "NFKD"
+)
+
 // Codec represents a token encoding codec.
 type Codec struct {
 	Name           string          `json:"name"`
@@ -27,38 +35,14 @@ type Codec struct {
 	PatStr         string          `json:"pat_str"`
 	MergeableRanks map[string]uint `json:"mergeable_ranks"`
 	SpecialTokens  map[string]uint `json:"special_tokens"`
+	// Normalization is the unicode normalization form (NFC, NFD, NFKC or NFKD) applied to
+	// the text before encoding. It is empty if the text is encoded as is.
+	Normalization string `json:"normalization,omitempty"`
 }
 
 // CovertVocabBPEAndEncoderJSONToMergeableBPERanks converts the vocabulary BPE and encoder JSON
 // to mergeable BPE ranks.
 func CovertVocabBPEAndEncoderJSONToMergeableBPERanks(vocabBPE io.Reader, encoderJSON io.Reader) (map[string]uint, error) {
-	rankToIntByte := make([]rune, 0)
-
-	for b := 0; b < 256; b++ {
-		if strconv.IsPrint(rune(b)) && rune(b) != rune(' ') {
-			rankToIntByte = append(rankToIntByte, rune(b))
-		}
-	}
-
-	dataGymByteToByte := make(map[string]rune)
-	for _, b := range rankToIntByte {
-		dataGymByteToByte[string(b)] = b
-	}
-
-	n := 0
-
-	for b := 0; b < 256; b++ {
-		if !containsRune(rankToIntByte, rune(b)) {
-			rankToIntByte = append(rankToIntByte, rune(b))
-			dataGymByteToByte[string(rune(256+n))] = rune(b)
-			n++
-		}
-	}
-
-	if len(rankToIntByte) != 256 {
-		return nil, errors.New("assertion failed: len(rankToIntByte) != 256")
-	}
-
 	vocabBPEContents, err := io.ReadAll(vocabBPE)
 	if err != nil {
 		return nil, err
@@ -70,40 +54,27 @@ func CovertVocabBPEAndEncoderJSONToMergeableBPERanks(vocabBPE io.Reader, encoder
 
 	for _, mergeStr := range vocabBPELines[1 : len(vocabBPELines)-1] {
 		merge := strings.Split(mergeStr, " ")
+		if len(merge) != 2 {
+			return nil, fmt.Errorf("invalid bpe merge: %q", mergeStr)
+		}
+
 		bpeMerges = append(bpeMerges, merge)
 	}
 
 	// add the single byte tokens
 	bpeRanks := make(map[string]uint)
 
-	for i, b := range rankToIntByte {
-		if i < 0 {
-			return nil, fmt.Errorf("negative value not allowed: %d", i)
-		}
-
-		key := string(b)
-		bpeRanks[key] = uint(i)
+	for i, b := range byteLevelOrder {
+		bpeRanks[string([]byte{b})] = uint(i)
 	}
 
 	// add the merged tokens
-	decodeDataGym := func(value string) []rune {
-		result := []rune{}
-		for _, c := range value {
-			result = append(result, dataGymByteToByte[string(c)])
-		}
-
-		return result
-	}
-
-	n = len(bpeRanks)
+	n := len(bpeRanks)
 
 	for _, merge := range bpeMerges {
-		first := decodeDataGym(merge[0])
-		second := decodeDataGym(merge[1])
-		key := string(append(first, second...))
-
-		if n < 0 {
-			return nil, fmt.Errorf("negative value not allowed: %d", n)
+		key, err := decodeByteLevel(merge[0] + merge[1])
+		if err != nil {
+			return nil, err
 		}
 
 		bpeRanks[key] = uint(n)
@@ -125,9 +96,9 @@ func CovertVocabBPEAndEncoderJSONToMergeableBPERanks(vocabBPE io.Reader, encoder
 	encoderLoaded := make(map[string]uint)
 
 	for k, v := range encoderMap {
-		result := []rune{}
-		for _, r := range k {
-			result = append(result, dataGymByteToByte[string(r)])
+		key, err := decodeByteLevel(k)
+		if err != nil {
+			return nil, err
 		}
 
 		if val, ok := v.(float64); ok {
@@ -135,7 +106,7 @@ func CovertVocabBPEAndEncoderJSONToMergeableBPERanks(vocabBPE io.Reader, encoder
 				return nil, fmt.Errorf("value out of uint range: %f", val)
 			}
 
-			encoderLoaded[string(result)] = uint(val)
+			encoderLoaded[key] = uint(val)
 		}
 	}
 
@@ -176,6 +147,9 @@ func ConvertToMergeableBPERanks(bpe io.Reader) (map[string]uint, error) {
 		}
 
 		parts := strings.Split(line, " ")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid bpe line: %q", line)
+		}
 
 		token, err := base64.StdEncoding.DecodeString(parts[0])
 		if err != nil {
@@ -196,14 +170,3 @@ func ConvertToMergeableBPERanks(bpe io.Reader) (map[string]uint, error) {
 
 	return bpeRanks, nil
 }
-
-// containsRune checks if a rune exists in an array of runes.
-func containsRune(arr []rune, b rune) bool {
-	for _, v := range arr {
-		if v == b {
-			return true
-		}
-	}
-
-	return false
-}
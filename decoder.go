@@ -0,0 +1,102 @@
+package tiktoken
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Decoder decodes a sequence of token IDs incrementally.
+//
+// A single token can carry part of a multi-byte UTF-8 character. The Decoder buffers such
+// incomplete sequences until the following tokens complete them, so the returned text never
+// contains split characters. Byte sequences that can never form a valid character are replaced
+// by the Unicode replacement character U+FFFD.
+type Decoder struct {
+	encoding *Encoding
+	buf      []byte
+}
+
+// NewDecoder returns a Decoder for the Encoding.
+func (enc *Encoding) NewDecoder() *Decoder {
+	return &Decoder{encoding: enc}
+}
+
+// Write decodes the token ID and returns the text that is complete so far.
+// Unknown token IDs are ignored, like in Decode.
+func (d *Decoder) Write(id uint) string {
+	tokenBytes, _ := d.encoding.coreBPE.tokenBytes(id)
+	d.buf = append(d.buf, tokenBytes...)
+
+	var sb strings.Builder
+
+	i := 0
+	for i < len(d.buf) && utf8.FullRune(d.buf[i:]) {
+		r, size := utf8.DecodeRune(d.buf[i:])
+		if r == utf8.RuneError && size == 1 {
+			sb.WriteRune(utf8.RuneError)
+		} else {
+			sb.Write(d.buf[i : i+size])
+		}
+
+		i += size
+	}
+
+	d.buf = d.buf[:copy(d.buf, d.buf[i:])]
+
+	return sb.String()
+}
+
+// Flush returns the buffered bytes of an incomplete character as U+FFFD and resets the Decoder.
+// It returns an empty string if no bytes are buffered.
+func (d *Decoder) Flush() string {
+	if len(d.buf) == 0 {
+		return ""
+	}
+
+	d.buf = d.buf[:0]
+
+	return string(utf8.RuneError)
+}
+
+// decodeReader reads the text of the token IDs received from a channel.
+type decodeReader struct {
+	decoder *Decoder
+	ids     <-chan uint
+	pending string
+	done    bool
+}
+
+// NewDecodeReader returns an io.Reader that reads the text of the token IDs received from ids.
+// The reader returns io.EOF after ids has been closed and all text has been read.
+func (enc *Encoding) NewDecodeReader(ids <-chan uint) io.Reader {
+	return &decodeReader{decoder: enc.NewDecoder(), ids: ids}
+}
+
+// Read implements io.Reader.
+func (r *decodeReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for r.pending == "" {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		id, ok := <-r.ids
+		if !ok {
+			r.pending = r.decoder.Flush()
+			r.done = true
+
+			continue
+		}
+
+		r.pending = r.decoder.Write(id)
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+
+	return n, nil
+}
@@ -0,0 +1,106 @@
+//go:build !tiktoken_minimal
+
+package tiktoken
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"testing/quick"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoder(t *testing.T) {
+	enc, err := NewEncodingByName(CL100kBase)
+	require.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+		f := func(text textWithSpecials) bool {
+			ids, _, err := enc.Encode(string(text), WithAllSpecialAllowed())
+			require.NoError(t, err)
+
+			d := enc.NewDecoder()
+
+			var sb strings.Builder
+
+			for _, id := range ids {
+				s := d.Write(id)
+				require.True(t, utf8.ValidString(s), "invalid UTF-8 %q", s)
+				sb.WriteString(s)
+			}
+
+			sb.WriteString(d.Flush())
+
+			return assert.Equal(t, string(text), sb.String())
+		}
+
+		require.NoError(t, quick.Check(f, nil))
+	})
+
+	t.Run("split character", func(t *testing.T) {
+		ids := enc.EncodeOrdinaryIDs("🙂")
+		require.Greater(t, len(ids), 1)
+
+		d := enc.NewDecoder()
+
+		for _, id := range ids[:len(ids)-1] {
+			assert.Empty(t, d.Write(id))
+		}
+
+		assert.Equal(t, "🙂", d.Write(ids[len(ids)-1]))
+		assert.Empty(t, d.Flush())
+	})
+
+	t.Run("invalid bytes", func(t *testing.T) {
+		d := enc.NewDecoder()
+
+		invalid := enc.coreBPE.encoder["\xff"]
+		partial := enc.coreBPE.encoder["\xe4"]
+
+		assert.Equal(t, "�", d.Write(invalid))
+		assert.Empty(t, d.Write(partial))
+		assert.Equal(t, "�a", d.Write(enc.coreBPE.encoder["a"]))
+		assert.Empty(t, d.Write(partial))
+		assert.Equal(t, "�", d.Flush())
+		assert.Empty(t, d.Flush())
+	})
+
+	t.Run("unknown id", func(t *testing.T) {
+		d := enc.NewDecoder()
+		assert.Empty(t, d.Write(1<<30))
+	})
+}
+
+func TestDecodeReader(t *testing.T) {
+	enc, err := NewEncodingByName(CL100kBase)
+	require.NoError(t, err)
+
+	text := "Hello 🙂 world, 你好世界!" + EndOfText
+
+	ids, _, err := enc.Encode(text, WithAllSpecialAllowed())
+	require.NoError(t, err)
+
+	newReader := func() io.Reader {
+		ch := make(chan uint)
+
+		go func() {
+			defer close(ch)
+
+			for _, id := range ids {
+				ch <- id
+			}
+		}()
+
+		return enc.NewDecodeReader(ch)
+	}
+
+	b, err := io.ReadAll(newReader())
+	require.NoError(t, err)
+	assert.Equal(t, text, string(b))
+
+	require.NoError(t, iotest.TestReader(newReader(), []byte(text)))
+}
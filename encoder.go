@@ -0,0 +1,222 @@
+package tiktoken
+
+import (
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dlclark/regexp2"
+)
+
+// encoderReadSize is the number of bytes an Encoder reads from its reader at once.
+const encoderReadSize = 32 * 1024
+
+// Encoder encodes text read from an io.Reader incrementally.
+//
+// For encodings with the r50k, cl100k or o200k pre-tokenization pattern, the text is encoded in
+// chunks that end between two pre-tokenized pieces. A chunk is only encoded once enough text
+// follows it to rule out that the pieces before its end, or a special token spanning it, change,
+// so the token IDs are identical to encoding the whole text at once. Only text within a single
+// piece, such as a long run of whitespace, is buffered until the piece ends. For all other patterns
+// the pieces cannot be determined incrementally, and the whole text is buffered and encoded once
+// the reader is exhausted.
+type Encoder struct {
+	encoding          *Encoding
+	r                 io.Reader
+	allowedSpecialSet map[string]any
+	disallowedRegex   *regexp2.Regexp
+	specials          []string
+	maxSpecialLen     int
+	nextPiece         pieceFunc
+	buf               []byte
+	retryLen          int
+	ids               []uint
+	pos               int
+	eof               bool
+	err               error
+}
+
+// NewEncoder returns an Encoder that encodes the text read from r with the given options, like Encode.
+func (enc *Encoding) NewEncoder(r io.Reader, opts ...EncodeOption) *Encoder {
+	if enc.normalization != nil {
+		r = enc.normalization.Reader(r)
+	}
+
+	allowedSpecialSet, disallowedRegex := enc.specialSets(newEncodeOptions(opts))
+
+	// only the hand-written pre-tokenizers are known to look a bounded distance ahead
+	nextPiece, _ := enc.coreBPE.preTokenizer.(pieceFunc)
+
+	specials := make([]string, 0, len(enc.coreBPE.specialTokensEncoder))
+	maxSpecialLen := 0
+
+	for token := range enc.coreBPE.specialTokensEncoder {
+		specials = append(specials, token)
+
+		if len(token) > maxSpecialLen {
+			maxSpecialLen = len(token)
+		}
+	}
+
+	return &Encoder{
+		encoding:          enc,
+		r:                 r,
+		allowedSpecialSet: allowedSpecialSet,
+		disallowedRegex:   disallowedRegex,
+		specials:          specials,
+		maxSpecialLen:     maxSpecialLen,
+		nextPiece:         nextPiece,
+	}
+}
+
+// Next returns the next token ID. It returns io.EOF once all text has been encoded, or the error
+// of the underlying reader. Encountering a disallowed special token is reported as an error, after
+// all tokens preceding the chunk containing it have been returned.
+func (e *Encoder) Next() (uint, error) {
+	for e.pos == len(e.ids) {
+		if e.err != nil {
+			return 0, e.err
+		}
+
+		e.ids, e.pos = e.ids[:0], 0
+		e.advance()
+	}
+
+	id := e.ids[e.pos]
+	e.pos++
+
+	return id, nil
+}
+
+// advance reads more text and encodes the buffered text up to the last point at which it can be
+// encoded independently of the text that follows.
+func (e *Encoder) advance() {
+	if !e.eof {
+		if cap(e.buf)-len(e.buf) < encoderReadSize {
+			buf := make([]byte, len(e.buf), 2*cap(e.buf)+encoderReadSize)
+			copy(buf, e.buf)
+			e.buf = buf
+		}
+
+		n, err := e.r.Read(e.buf[len(e.buf):cap(e.buf)])
+		e.buf = e.buf[:len(e.buf)+n]
+
+		if err == io.EOF {
+			e.eof = true
+		} else if err != nil {
+			e.err = err
+			return
+		}
+	}
+
+	// Searching for a split point takes time linear in the buffered text, so after a failed
+	// search the next one waits until the buffer has doubled.
+	if !e.eof && (e.nextPiece == nil || len(e.buf) < e.retryLen) {
+		return
+	}
+
+	text := string(e.buf)
+
+	end := len(text)
+	if !e.eof {
+		end = e.splitPoint(text)
+		if end == 0 {
+			e.retryLen = 2 * len(text)
+			return
+		}
+	}
+
+	e.retryLen = 0
+
+	if err := checkDisallowedSpecial(text[:end], e.disallowedRegex); err != nil {
+		e.err = err
+		return
+	}
+
+	e.ids = e.encoding.coreBPE.AppendEncode(e.ids, text[:end], e.allowedSpecialSet)
+	e.buf = e.buf[:copy(e.buf, e.buf[end:])]
+
+	if e.eof && len(e.buf) == 0 {
+		e.err = io.EOF
+	}
+}
+
+// splitPoint returns the last byte offset of the buffered text at which the pieces before it are
+// final and no special token is split, or 0 if there is none.
+//
+// The hand-written pre-tokenizers look at most two runes past the end of the piece following a
+// piece, so the pieces before an offset are final once the piece starting there ends 2*UTFMax bytes
+// before the end of the buffered text. Another maxSpecialLen bytes leave room for a special token
+// that is not complete yet.
+func (e *Encoder) splitPoint(text string) int {
+	limit := len(text) - e.maxSpecialLen - 2*utf8.UTFMax
+	if limit <= 0 {
+		return 0
+	}
+
+	// Allowed special tokens that have maxSpecialLen bytes of text left are complete and end a
+	// pre-tokenized segment. Later ones may still be incomplete and start after limit.
+	start := 0
+
+	if len(e.allowedSpecialSet) > 0 {
+		for {
+			m := e.encoding.coreBPE.findNextAllowedSpecial(text, start, e.allowedSpecialSet)
+			if m == nil || m[0] > len(text)-e.maxSpecialLen {
+				break
+			}
+
+			start = m[1]
+		}
+	}
+
+	split := start
+
+	for i := start; i < limit; {
+		end := e.nextPiece(text, i)
+		if end > limit {
+			break
+		}
+
+		if i > split && !endsSpaceRun(text, i) && !e.insideSpecial(text, i) {
+			split = i
+		}
+
+		i = end
+	}
+
+	return split
+}
+
+// endsSpaceRun reports whether the byte offset i of text follows two whitespace runes. Cutting the
+// text there can change its pieces, as \s+(?!\S) then matches the whole whitespace run.
+func endsSpaceRun(text string, i int) bool {
+	r1, n1 := utf8.DecodeLastRuneInString(text[:i])
+	if !unicode.IsSpace(r1) {
+		return false
+	}
+
+	r2, _ := utf8.DecodeLastRuneInString(text[:i-n1])
+
+	return unicode.IsSpace(r2)
+}
+
+// insideSpecial reports whether the byte offset i of text lies strictly inside a special token.
+func (e *Encoder) insideSpecial(text string, i int) bool {
+	for _, token := range e.specials {
+		from, to := i-len(token)+1, i+len(token)-1
+		if from < 0 {
+			from = 0
+		}
+
+		if to > len(text) {
+			to = len(text)
+		}
+
+		if strings.Contains(text[from:to], token) {
+			return true
+		}
+	}
+
+	return false
+}
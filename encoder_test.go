@@ -0,0 +1,176 @@
+//go:build !tiktoken_minimal
+
+package tiktoken
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readAllIDs drains the encoder and returns the token IDs together with the terminating error.
+func readAllIDs(e *Encoder) ([]uint, error) {
+	var ids []uint
+
+	for {
+		id, err := e.Next()
+		if err != nil {
+			return ids, err
+		}
+
+		ids = append(ids, id)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	readers := map[string]func(string) io.Reader{
+		"whole":    func(s string) io.Reader { return strings.NewReader(s) },
+		"one byte": func(s string) io.Reader { return iotest.OneByteReader(strings.NewReader(s)) },
+		"half":     func(s string) io.Reader { return iotest.HalfReader(strings.NewReader(s)) },
+	}
+
+	for _, name := range []string{CL100kBase, O200kBase, R50kBase, Claude} {
+		enc, err := NewEncodingByName(name)
+		require.NoError(t, err)
+
+		for readerName, newReader := range readers {
+			newReader := newReader
+
+			t.Run(name+"/"+readerName, func(t *testing.T) {
+				f := func(text textWithSpecials) bool {
+					expected, _, err := enc.Encode(string(text), WithAllSpecialAllowed())
+					require.NoError(t, err)
+
+					ids, err := readAllIDs(enc.NewEncoder(newReader(string(text)), WithAllSpecialAllowed()))
+					require.ErrorIs(t, err, io.EOF)
+
+					if len(expected) == 0 {
+						return assert.Empty(t, ids, "text %q", text)
+					}
+
+					return assert.Equal(t, expected, ids, "text %q", text)
+				}
+
+				require.NoError(t, quick.Check(f, &quick.Config{MaxCount: 50, MaxCountScale: 0}))
+			})
+		}
+	}
+}
+
+func TestEncoderLongText(t *testing.T) {
+	enc, err := NewEncodingByName(CL100kBase)
+	require.NoError(t, err)
+
+	text := strings.Repeat(benchmarkText+EndOfText, 20) + strings.Repeat("x", 3*encoderReadSize)
+
+	expected, _, err := enc.Encode(text, WithAllSpecialAllowed())
+	require.NoError(t, err)
+
+	ids, err := readAllIDs(enc.NewEncoder(strings.NewReader(text), WithAllSpecialAllowed()))
+	require.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, expected, ids)
+}
+
+func TestEncoderChunks(t *testing.T) {
+	texts := map[string]string{
+		"cjk":   strings.Repeat("敏捷的棕色狐狸跳过了懒狗。\n", 20000),
+		"jsonl": strings.Repeat(`{"id":42,"text":"quick_brown_fox"}`+"\n", 20000),
+		"code":  strings.Repeat("\tif(x>0){\n\t\ty=f(x);\n\t}\n", 20000),
+	}
+
+	for _, name := range []string{CL100kBase, O200kBase, R50kBase} {
+		enc, err := NewEncodingByName(name)
+		require.NoError(t, err)
+
+		for textName, text := range texts {
+			text := text
+
+			t.Run(name+"/"+textName, func(t *testing.T) {
+				r := strings.NewReader(text)
+				e := enc.NewEncoder(r)
+
+				first, err := e.Next()
+				require.NoError(t, err)
+				assert.Greater(t, r.Len(), len(text)/2, "first token after reading the whole text")
+
+				ids, err := readAllIDs(e)
+				require.ErrorIs(t, err, io.EOF)
+
+				expected, err := enc.EncodeIDs(text)
+				require.NoError(t, err)
+				assert.Equal(t, expected, append([]uint{first}, ids...))
+				assert.LessOrEqual(t, cap(e.buf), 4*encoderReadSize)
+			})
+		}
+	}
+}
+
+func TestEncoderCustomPattern(t *testing.T) {
+	// the pattern keeps trailing whitespace with the preceding word
+	enc, err := NewEncoding(&Codec{
+		Name:           "custom",
+		PatStr:         `\S+\s+|\S+|\s+`,
+		MergeableRanks: map[string]uint{"a": 0, " ": 1, "b": 2, "a ": 3, "b ": 4},
+	})
+	require.NoError(t, err)
+
+	text := strings.Repeat("a b ", 10)
+
+	expected, err := enc.EncodeIDs(text)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{3, 4, 3, 4}, expected[:4])
+
+	ids, err := readAllIDs(enc.NewEncoder(iotest.OneByteReader(strings.NewReader(text))))
+	require.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, expected, ids)
+}
+
+func TestEncoderErrors(t *testing.T) {
+	enc, err := NewEncodingByName(CL100kBase)
+	require.NoError(t, err)
+
+	t.Run("disallowed special", func(t *testing.T) {
+		e := enc.NewEncoder(strings.NewReader("hello world" + EndOfText))
+
+		_, err := readAllIDs(e)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, io.EOF)
+
+		// errors are sticky
+		_, again := e.Next()
+		assert.Equal(t, err, again)
+	})
+
+	t.Run("reader error", func(t *testing.T) {
+		readErr := errors.New("read failed")
+
+		_, err := readAllIDs(enc.NewEncoder(iotest.ErrReader(readErr)))
+		assert.ErrorIs(t, err, readErr)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		ids, err := readAllIDs(enc.NewEncoder(strings.NewReader("")))
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Empty(t, ids)
+	})
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	enc, err := NewEncodingByName(CL100kBase)
+	require.NoError(b, err)
+
+	text := strings.Repeat(benchmarkText, 10)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(text)))
+
+	for i := 0; i < b.N; i++ {
+		_, _ = readAllIDs(enc.NewEncoder(strings.NewReader(text)))
+	}
+}
@@ -7,44 +7,34 @@ import (
 	"strings"
 
 	"github.com/dlclark/regexp2"
+	"golang.org/x/text/unicode/norm"
 )
 
+// TokenOffset is the byte range [Start, End) of a token in the encoded text.
+type TokenOffset struct {
+	Start int
+	End   int
+}
+
 // Encoding represents a text encoding scheme.
+// An Encoding is immutable once created and safe for concurrent use by multiple goroutines.
 type Encoding struct {
 	name             string
 	specialTokensSet map[string]any
 	coreBPE          *coreBPE
+	normalization    *norm.Form
 }
 
-// NewEncodingByName creates a new Encoding instance based on the given encoding name.
+// NewEncodingByName returns the Encoding registered under the given encoding name.
+// Encodings are built once per process on first use and shared by all callers;
+// use UnloadEncoding to release an Encoding that is no longer needed.
 func NewEncodingByName(encoding string) (*Encoding, error) {
-	var (
-		codec *Codec
-		err   error
-	)
-
-	switch encoding {
-	case O200kBase:
-		codec, err = NewO200KBase()
-	case CL100kBase:
-		codec, err = NewCL100kBase()
-	case P50kBase:
-		codec, err = NewP50kBase()
-	case P50kEdit:
-		codec, err = NewP50kEdit()
-	case R50kBase:
-		codec, err = NewR50kBase()
-	case GPT2:
-		codec, err = NewGPT2()
-	default:
+	newCodec, ok := lookupCodec(encoding)
+	if !ok {
 		return nil, fmt.Errorf("unknown encoding: %s", encoding)
 	}
 
-	if err != nil {
-		return nil, err
-	}
-
-	return NewEncoding(codec)
+	return loadEncoding(encoding, newCodec)
 }
 
 // NewEncoding creates a new Encoding instance based on the provided Codec.
@@ -54,6 +44,11 @@ func NewEncoding(codec *Codec) (*Encoding, error) {
 		return nil, err
 	}
 
+	normalization, err := newNormalizer(codec.Normalization)
+	if err != nil {
+		return nil, err
+	}
+
 	specialTokensSet := map[string]any{}
 	for k := range codec.SpecialTokens {
 		specialTokensSet[k] = true
@@ -63,6 +58,7 @@ func NewEncoding(codec *Codec) (*Encoding, error) {
 		name:             codec.Name,
 		specialTokensSet: specialTokensSet,
 		coreBPE:          coreBPE,
+		normalization:    normalization,
 	}, nil
 }
 
@@ -71,46 +67,187 @@ func (enc *Encoding) Name() string {
 	return enc.name
 }
 
+// SpecialTokens returns the special tokens of the Encoding and their IDs.
+// The returned map is a copy and may be modified by the caller.
+func (enc *Encoding) SpecialTokens() map[string]uint {
+	specialTokens := make(map[string]uint, len(enc.coreBPE.specialTokensEncoder))
+	for k, v := range enc.coreBPE.specialTokensEncoder {
+		specialTokens[k] = v
+	}
+
+	return specialTokens
+}
+
 // EncodeOrdinary encodes the given text using the Encoding's core BPE.
 func (enc *Encoding) EncodeOrdinary(text string) ([]uint, []string) {
-	return enc.coreBPE.EncodeOrdinary(text)
+	return enc.coreBPE.EncodeOrdinary(enc.normalizeText(text))
+}
+
+// EncodeOrdinaryWithOffsets encodes the given text like EncodeOrdinary and returns the byte range
+// of every token in text.
+func (enc *Encoding) EncodeOrdinaryWithOffsets(text string) ([]uint, []TokenOffset) {
+	normalized, mapping := enc.normalizeTextWithOffsets(text)
+
+	ids := []uint{}
+	offsets := []TokenOffset{}
+
+	var scratch []mergePart
+
+	enc.coreBPE.encodeOrdinary(normalized, 0, len(normalized), &scratch, func(id uint, start, end int) {
+		ids = append(ids, id)
+		offsets = append(offsets, mapping.span(start, end))
+	})
+
+	return ids, offsets
+}
+
+// Encode encodes the given text, treating special tokens as configured by the options.
+// By default, text containing a special token is rejected with an error.
+func (enc *Encoding) Encode(text string, opts ...EncodeOption) ([]uint, []string, error) {
+	text = enc.normalizeText(text)
+
+	allowedSpecialSet, err := enc.allowedSpecialSet(text, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids, tokens := enc.coreBPE.Encode(text, allowedSpecialSet)
+
+	return ids, tokens, nil
+}
+
+// EncodeWithOffsets encodes the given text like Encode and returns the byte range of every token in text.
+// A token holding only part of a multi-byte UTF-8 character has a range that starts or ends inside
+// that character; the ranges of consecutive tokens are always contiguous. If the Encoding normalizes
+// its input, the ranges refer to the original text and tokens produced from the same normalized
+// character sequence share its range.
+func (enc *Encoding) EncodeWithOffsets(text string, opts ...EncodeOption) ([]uint, []TokenOffset, error) {
+	normalized, mapping := enc.normalizeTextWithOffsets(text)
+
+	allowedSpecialSet, err := enc.allowedSpecialSet(normalized, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := []uint{}
+	offsets := []TokenOffset{}
+
+	enc.coreBPE.encode(normalized, allowedSpecialSet, func(id uint, start, end int) {
+		ids = append(ids, id)
+		offsets = append(offsets, mapping.span(start, end))
+	})
+
+	return ids, offsets, nil
+}
+
+// EncodeOrdinaryIDs encodes the given text like EncodeOrdinary but only returns the token IDs.
+func (enc *Encoding) EncodeOrdinaryIDs(text string) []uint {
+	return enc.AppendEncodeOrdinary(nil, text)
+}
+
+// AppendEncodeOrdinary appends the token IDs of the given text, encoded like EncodeOrdinary, to dst
+// and returns the extended slice. Reusing dst across calls avoids allocations on hot paths.
+func (enc *Encoding) AppendEncodeOrdinary(dst []uint, text string) []uint {
+	return enc.coreBPE.AppendEncodeOrdinary(dst, enc.normalizeText(text))
+}
+
+// EncodeIDs encodes the given text like Encode but only returns the token IDs.
+func (enc *Encoding) EncodeIDs(text string, opts ...EncodeOption) ([]uint, error) {
+	return enc.AppendEncode(nil, text, opts...)
+}
+
+// AppendEncode appends the token IDs of the given text, encoded like Encode, to dst and returns the
+// extended slice. Reusing dst across calls avoids allocations on hot paths. If the text contains a
+// disallowed special token, dst is returned unchanged together with the error.
+func (enc *Encoding) AppendEncode(dst []uint, text string, opts ...EncodeOption) ([]uint, error) {
+	text = enc.normalizeText(text)
+
+	allowedSpecialSet, err := enc.allowedSpecialSet(text, opts)
+	if err != nil {
+		return dst, err
+	}
+
+	return enc.coreBPE.AppendEncode(dst, text, allowedSpecialSet), nil
+}
+
+// CountOrdinary returns the number of tokens EncodeOrdinary would produce for the given text.
+// It neither allocates token IDs nor token strings.
+func (enc *Encoding) CountOrdinary(text string) int {
+	return enc.coreBPE.CountOrdinary(enc.normalizeText(text))
+}
+
+// Count returns the number of tokens Encode would produce for the given text with the same options.
+// It neither allocates token IDs nor token strings.
+func (enc *Encoding) Count(text string, opts ...EncodeOption) (int, error) {
+	text = enc.normalizeText(text)
+
+	allowedSpecialSet, err := enc.allowedSpecialSet(text, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	return enc.coreBPE.Count(text, allowedSpecialSet), nil
 }
 
-var AllSpecial = []string{"all"}
+// allowedSpecialSet resolves the options into the set of special tokens that may be encoded as such.
+// It returns an error if text contains a disallowed special token.
+func (enc *Encoding) allowedSpecialSet(text string, opts []EncodeOption) (map[string]any, error) {
+	allowedSpecialSet, disallowedRegex := enc.specialSets(newEncodeOptions(opts))
+
+	if err := checkDisallowedSpecial(text, disallowedRegex); err != nil {
+		return nil, err
+	}
+
+	return allowedSpecialSet, nil
+}
 
-// Encode encodes the given text with the specified allowed and disallowed special tokens.
-func (enc *Encoding) Encode(text string, allowedSpecial, disallowedSpecial []string) ([]uint, []string, error) {
+// specialSets resolves the options. It returns the set of special tokens that may be encoded as such
+// and a regular expression matching the disallowed special tokens, which is nil if no special token
+// is disallowed.
+func (enc *Encoding) specialSets(opts encodeOptions) (map[string]any, *regexp2.Regexp) {
 	var allowedSpecialSet map[string]any
-	if len(allowedSpecial) == 1 && allowedSpecial[0] == "all" {
+	if opts.allowAllSpecial {
 		allowedSpecialSet = enc.specialTokensSet
 	} else {
 		allowedSpecialSet = map[string]any{}
-		for _, v := range allowedSpecial {
+		for _, v := range opts.allowedSpecial {
 			allowedSpecialSet[v] = nil
 		}
 	}
 
-	disallowedSpecialSet := map[string]any{}
-	for _, v := range disallowedSpecial {
-		disallowedSpecialSet[v] = nil
-	}
+	var disallowedSpecialSet map[string]any
+	if opts.disallowAll {
+		if len(allowedSpecialSet) == 0 {
+			// the default: every special token is disallowed
+			return allowedSpecialSet, enc.coreBPE.tlSpecialRegex
+		}
 
-	if len(disallowedSpecial) == 1 && disallowedSpecial[0] == "all" {
 		disallowedSpecialSet = difference(enc.specialTokensSet, allowedSpecialSet)
+	} else {
+		disallowedSpecialSet = map[string]any{}
+		for _, v := range opts.disallowedSpecial {
+			disallowedSpecialSet[v] = nil
+		}
 	}
 
-	if len(disallowedSpecialSet) > 0 {
-		specialRegex := specialTokenRegex(disallowedSpecialSet)
+	if len(disallowedSpecialSet) == 0 {
+		return allowedSpecialSet, nil
+	}
 
-		m := findRegex2StringMatch(text, specialRegex)
-		if m != "" {
-			return nil, nil, fmt.Errorf("text contains disallowed special token %s", m)
-		}
+	return allowedSpecialSet, specialTokenRegex(disallowedSpecialSet)
+}
+
+// checkDisallowedSpecial returns an error if text contains a match of disallowedRegex.
+func checkDisallowedSpecial(text string, disallowedRegex *regexp2.Regexp) error {
+	if disallowedRegex == nil {
+		return nil
 	}
 
-	ids, tokens := enc.coreBPE.Encode(text, allowedSpecialSet)
+	if m := findRegex2StringMatch(text, disallowedRegex); m != "" {
+		return fmt.Errorf("text contains disallowed special token %s", m)
+	}
 
-	return ids, tokens, nil
+	return nil
 }
 
 // Decode decodes the given tokens using the Encoding's core BPE.
@@ -118,6 +255,88 @@ func (enc *Encoding) Decode(tokens []uint) []byte {
 	return enc.coreBPE.Decode(tokens)
 }
 
+// UnknownTokensError is returned by DecodeStrict if token IDs are neither ordinary nor special tokens of the Encoding.
+type UnknownTokensError struct {
+	IDs       []uint // the unknown token IDs
+	Positions []int  // the positions of the unknown token IDs in the decoded sequence
+}
+
+// Error implements the error interface.
+func (e *UnknownTokensError) Error() string {
+	const maxListed = 10
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%d unknown token ids:", len(e.IDs))
+
+	for i, id := range e.IDs {
+		if i == maxListed {
+			sb.WriteString(" ...")
+			break
+		}
+
+		fmt.Fprintf(&sb, " %d at position %d", id, e.Positions[i])
+
+		if i < len(e.IDs)-1 && i < maxListed-1 {
+			sb.WriteByte(',')
+		}
+	}
+
+	return sb.String()
+}
+
+// DecodeStrict decodes the given tokens like Decode, but fails with an *UnknownTokensError listing
+// every token ID that is not part of the Encoding instead of dropping them.
+func (enc *Encoding) DecodeStrict(tokens []uint) ([]byte, error) {
+	ret := make([]byte, 0, len(tokens)*2)
+
+	var unknown *UnknownTokensError
+
+	for i, token := range tokens {
+		tokenBytes, ok := enc.coreBPE.tokenBytes(token)
+		if !ok {
+			if unknown == nil {
+				unknown = &UnknownTokensError{}
+			}
+
+			unknown.IDs = append(unknown.IDs, token)
+			unknown.Positions = append(unknown.Positions, i)
+
+			continue
+		}
+
+		ret = append(ret, tokenBytes...)
+	}
+
+	if unknown != nil {
+		return nil, unknown
+	}
+
+	return ret, nil
+}
+
+// DecodeWithPlaceholder decodes the given tokens like Decode, but renders every token ID that is
+// not part of the Encoding with the given placeholder function instead of dropping it.
+// UnknownTokenPlaceholder can be used as a default.
+func (enc *Encoding) DecodeWithPlaceholder(tokens []uint, placeholder func(id uint) string) []byte {
+	ret := make([]byte, 0, len(tokens)*2)
+
+	for _, token := range tokens {
+		if tokenBytes, ok := enc.coreBPE.tokenBytes(token); ok {
+			ret = append(ret, tokenBytes...)
+		} else {
+			ret = append(ret, placeholder(token)...)
+		}
+	}
+
+	return ret
+}
+
+// UnknownTokenPlaceholder renders an unknown token ID as "<|unknown:ID|>".
+func UnknownTokenPlaceholder(id uint) string {
+	return fmt.Sprintf("<|unknown:%d|>", id)
+}
+
 // difference calculates the set difference between setA and setB.
 func difference(setA, setB map[string]any) map[string]any {
 	result := make(map[string]any)
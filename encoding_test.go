@@ -1,8 +1,12 @@
+//go:build !tiktoken_minimal
+
 package tiktoken
 
 import (
 	"crypto/sha256"
 	"fmt"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/dlclark/regexp2"
@@ -37,7 +41,7 @@ func TestGPT2Encoding(t *testing.T) {
 
 	t.Run("special token", func(t *testing.T) {
 		text := "hello <|endoftext|>"
-		ids, _, err := encoding.Encode(text, []string{"all"}, nil)
+		ids, _, err := encoding.Encode(text, WithAllSpecialAllowed())
 		assert.NoError(t, err)
 		assert.ElementsMatch(t, []uint{31373, 220, 50256}, ids)
 	})
@@ -46,9 +50,16 @@ func TestGPT2Encoding(t *testing.T) {
 		assert.Equal(t, "hello world", string(encoding.Decode([]uint{31373, 995})))
 	})
 
+	t.Run("non-ascii", func(t *testing.T) {
+		text := "héllo wörld 你好"
+		ids, _ := encoding.EncodeOrdinary(text)
+		assert.Equal(t, []uint{71, 2634, 18798, 266, 30570, 335, 220, 19526, 254, 25001, 121}, ids)
+		assert.Equal(t, text, string(encoding.Decode(ids)))
+	})
+
 	t.Run("not allowed", func(t *testing.T) {
 		text := "hello <|endoftext|>"
-		_, _, err := encoding.Encode(text, nil, []string{"<|endoftext|>"})
+		_, _, err := encoding.Encode(text, WithDisallowedSpecial("<|endoftext|>"))
 		assert.Error(t, err)
 	})
 }
@@ -64,23 +75,24 @@ func TestCL100kEncoding(t *testing.T) {
 	})
 
 	t.Run("hash", func(t *testing.T) {
-		h := sha256.New()
-		h.Write([]byte(cl100kBase))
-		bs := h.Sum(nil)
+		data, err := os.ReadFile("resource/cl100k_base.tiktoken")
+		assert.NoError(t, err)
+
+		bs := sha256.Sum256(data)
 
 		assert.Equal(t, "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7", fmt.Sprintf("%x", bs))
 	})
 
 	t.Run("special token", func(t *testing.T) {
 		text := "hello <|endoftext|>"
-		ids, _, err := encoding.Encode(text, []string{"all"}, nil)
+		ids, _, err := encoding.Encode(text, WithAllSpecialAllowed())
 		assert.NoError(t, err)
 		assert.ElementsMatch(t, []uint{15339, 220, 100257}, ids)
 	})
 
 	t.Run("chinese", func(t *testing.T) {
 		text := "你好世界！"
-		ids, _, err := encoding.Encode(text, []string{"all"}, nil)
+		ids, _, err := encoding.Encode(text, WithAllSpecialAllowed())
 		assert.NoError(t, err)
 		assert.ElementsMatch(t, []uint{57668, 53901, 3574, 244, 98220, 6447}, ids)
 	})
@@ -91,7 +103,7 @@ func TestCL100kEncoding(t *testing.T) {
 
 	t.Run("not allowed", func(t *testing.T) {
 		text := "hello <|endoftext|>"
-		_, _, err := encoding.Encode(text, nil, []string{"<|endoftext|>"})
+		_, _, err := encoding.Encode(text, WithDisallowedSpecial("<|endoftext|>"))
 		assert.Error(t, err)
 	})
 }
@@ -107,16 +119,17 @@ func TestO200kEncoding(t *testing.T) {
 	})
 
 	t.Run("hash", func(t *testing.T) {
-		h := sha256.New()
-		h.Write([]byte(o200kBase))
-		bs := h.Sum(nil)
+		data, err := os.ReadFile("resource/o200k_base.tiktoken")
+		assert.NoError(t, err)
+
+		bs := sha256.Sum256(data)
 
 		assert.Equal(t, "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d", fmt.Sprintf("%x", bs))
 	})
 
 	t.Run("special token", func(t *testing.T) {
 		text := "hello <|endoftext|>"
-		ids, _, err := encoding.Encode(text, []string{"all"}, nil)
+		ids, _, err := encoding.Encode(text, WithAllSpecialAllowed())
 		assert.NoError(t, err)
 		assert.ElementsMatch(t, []uint{24912, 220, 199999}, ids)
 	})
@@ -127,7 +140,7 @@ func TestO200kEncoding(t *testing.T) {
 
 	t.Run("not allowed", func(t *testing.T) {
 		text := "hello <|endoftext|>"
-		_, _, err := encoding.Encode(text, nil, []string{"<|endoftext|>"})
+		_, _, err := encoding.Encode(text, WithDisallowedSpecial("<|endoftext|>"))
 		assert.Error(t, err)
 	})
 }
@@ -250,3 +263,270 @@ func TestDifference(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodeWithOffsets(t *testing.T) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	assert.NoError(t, err)
+
+	t.Run("splits multi-byte characters", func(t *testing.T) {
+		text := "你好世界！"
+		ids, offsets := encoding.EncodeOrdinaryWithOffsets(text)
+		assert.Equal(t, []uint{57668, 53901, 3574, 244, 98220, 6447}, ids)
+		assert.Equal(t, []TokenOffset{{0, 3}, {3, 6}, {6, 8}, {8, 9}, {9, 12}, {12, 15}}, offsets)
+	})
+
+	t.Run("special token", func(t *testing.T) {
+		text := "héllo <|endoftext|> world"
+		ids, offsets, err := encoding.EncodeWithOffsets(text, WithAllSpecialAllowed())
+		assert.NoError(t, err)
+		assert.Len(t, offsets, len(ids))
+
+		var pieces []string
+		for _, o := range offsets {
+			pieces = append(pieces, text[o.Start:o.End])
+		}
+
+		assert.Equal(t, []string{"h", "él", "lo", " ", "<|endoftext|>", " world"}, pieces)
+	})
+
+	t.Run("not allowed", func(t *testing.T) {
+		_, _, err := encoding.EncodeWithOffsets("hello <|endoftext|>", WithDisallowedSpecial("<|endoftext|>"))
+		assert.Error(t, err)
+	})
+
+	t.Run("normalized text", func(t *testing.T) {
+		claude, err := NewEncodingByName(Claude)
+		assert.NoError(t, err)
+
+		// "™" is normalized to "TM"
+		ids, offsets := claude.EncodeOrdinaryWithOffsets("a ™ b")
+		assert.Equal(t, []uint{69, 25862, 301}, ids)
+		assert.Equal(t, []TokenOffset{{0, 1}, {1, 5}, {5, 7}}, offsets)
+	})
+}
+
+func TestCount(t *testing.T) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	assert.NoError(t, err)
+
+	t.Run("ordinary", func(t *testing.T) {
+		text := "hello world <|endoftext|> 你好世界！"
+		ids, _ := encoding.EncodeOrdinary(text)
+		assert.Equal(t, len(ids), encoding.CountOrdinary(text))
+	})
+
+	t.Run("special token", func(t *testing.T) {
+		n, err := encoding.Count("hello <|endoftext|>", WithAllSpecialAllowed())
+		assert.NoError(t, err)
+		assert.Equal(t, 3, n)
+	})
+
+	t.Run("not allowed", func(t *testing.T) {
+		_, err := encoding.Count("hello <|endoftext|>", WithDisallowedSpecial("<|endoftext|>"))
+		assert.Error(t, err)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		assert.Equal(t, 0, encoding.CountOrdinary(""))
+	})
+}
+
+var benchmarkText = strings.Repeat("The quick brown fox jumps over the lazy dog. Der schnelle braune Fuchs springt über den faulen Hund. 敏捷的棕色狐狸跳过了懒狗。\n", 50)
+
+func BenchmarkEncode(b *testing.B) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _, _ = encoding.Encode(benchmarkText)
+	}
+}
+
+func BenchmarkCount(b *testing.B) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = encoding.Count(benchmarkText)
+	}
+}
+
+func BenchmarkCountOrdinary(b *testing.B) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("encode", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			_ = encoding.EncodeOrdinaryIDs(benchmarkText)
+		}
+	})
+
+	b.Run("count", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			_ = encoding.CountOrdinary(benchmarkText)
+		}
+	})
+}
+
+func TestEncodeIDs(t *testing.T) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	assert.NoError(t, err)
+
+	t.Run("ordinary", func(t *testing.T) {
+		assert.Equal(t, []uint{15339, 1917}, encoding.EncodeOrdinaryIDs("hello world"))
+	})
+
+	t.Run("special token", func(t *testing.T) {
+		ids, err := encoding.EncodeIDs("hello <|endoftext|>", WithAllSpecialAllowed())
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{15339, 220, 100257}, ids)
+	})
+
+	t.Run("not allowed", func(t *testing.T) {
+		_, err := encoding.EncodeIDs("hello <|endoftext|>", WithDisallowedSpecial("<|endoftext|>"))
+		assert.Error(t, err)
+	})
+
+	t.Run("append", func(t *testing.T) {
+		buf := make([]uint, 0, 16)
+
+		buf = encoding.AppendEncodeOrdinary(buf, "hello")
+		buf, err = encoding.AppendEncode(buf, " world<|endoftext|>", WithAllSpecialAllowed())
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{15339, 1917, 100257}, buf)
+
+		buf, err = encoding.AppendEncode(buf[:0], "<|endoftext|>", WithDisallowedSpecial("<|endoftext|>"))
+		assert.Error(t, err)
+		assert.Empty(t, buf)
+	})
+
+	t.Run("allocations", func(t *testing.T) {
+		buf := encoding.AppendEncodeOrdinary(nil, benchmarkText)
+		assert.Greater(t, len(buf), 1000)
+
+		// a few allocations per call, not per piece
+		allocs := testing.AllocsPerRun(10, func() {
+			buf = encoding.AppendEncodeOrdinary(buf[:0], benchmarkText)
+		})
+		assert.Less(t, allocs, float64(len(buf))/100)
+
+		allocs = testing.AllocsPerRun(10, func() {
+			buf, _ = encoding.AppendEncode(buf[:0], benchmarkText)
+		})
+		assert.Less(t, allocs, float64(len(buf))/100)
+	})
+}
+
+func BenchmarkAppendEncode(b *testing.B) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var buf []uint
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		buf, _ = encoding.AppendEncode(buf[:0], benchmarkText)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	assert.NoError(t, err)
+
+	t.Run("known tokens", func(t *testing.T) {
+		text, err := encoding.DecodeStrict([]uint{15339, 1917, 100257})
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world<|endoftext|>", string(text))
+	})
+
+	t.Run("unknown tokens", func(t *testing.T) {
+		_, err := encoding.DecodeStrict([]uint{15339, 200000, 1917, 100256})
+
+		var unknownErr *UnknownTokensError
+
+		assert.ErrorAs(t, err, &unknownErr)
+		assert.Equal(t, []uint{200000, 100256}, unknownErr.IDs)
+		assert.Equal(t, []int{1, 3}, unknownErr.Positions)
+		assert.EqualError(t, err, "2 unknown token ids: 200000 at position 1, 100256 at position 3")
+	})
+
+	t.Run("placeholder", func(t *testing.T) {
+		text := encoding.DecodeWithPlaceholder([]uint{15339, 200000, 1917}, UnknownTokenPlaceholder)
+		assert.Equal(t, "hello<|unknown:200000|> world", string(text))
+	})
+}
+
+func TestEncodeOptions(t *testing.T) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	assert.NoError(t, err)
+
+	text := "hello <|endoftext|><|fim_prefix|>"
+
+	tests := []struct {
+		name     string
+		opts     []EncodeOption
+		expected []uint
+		wantErr  bool
+	}{
+		{name: "default disallows all", wantErr: true},
+		{name: "all allowed", opts: []EncodeOption{WithAllSpecialAllowed()}, expected: []uint{15339, 220, 100257, 100258}},
+		{name: "allowed subset", opts: []EncodeOption{WithAllowedSpecial(EndOfText)}, wantErr: true},
+		{name: "allowed subset as text", opts: []EncodeOption{WithAllowedSpecial(EndOfText), WithSpecialAsText()}, expected: []uint{15339, 220, 100257, 27, 91, 69, 318, 14301, 91, 29}},
+		{name: "allowed both", opts: []EncodeOption{WithAllowedSpecial(EndOfText, FimPrefix)}, expected: []uint{15339, 220, 100257, 100258}},
+		{name: "special as text", opts: []EncodeOption{WithSpecialAsText()}, expected: []uint{15339, 83739, 8862, 728, 428, 91, 1822, 91, 69, 318, 14301, 91, 29}},
+		{name: "disallowed other", opts: []EncodeOption{WithDisallowedSpecial(EndOfPrompt)}, expected: []uint{15339, 83739, 8862, 728, 428, 91, 1822, 91, 69, 318, 14301, 91, 29}},
+		{name: "disallowed present", opts: []EncodeOption{WithDisallowedSpecial(FimPrefix)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := encoding.EncodeIDs(text, tt.opts...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
+
+func TestSpecialTokens(t *testing.T) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	assert.NoError(t, err)
+
+	specialTokens := encoding.SpecialTokens()
+	assert.Equal(t, map[string]uint{
+		EndOfText:   100257,
+		FimPrefix:   100258,
+		FimMiddle:   100259,
+		FimSuffix:   100260,
+		EndOfPrompt: 100276,
+	}, specialTokens)
+
+	delete(specialTokens, EndOfText)
+	assert.Contains(t, encoding.SpecialTokens(), EndOfText)
+}
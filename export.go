@@ -0,0 +1,312 @@
+package tiktoken
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// WriteTiktoken writes the mergeable ranks of the codec to w in the .tiktoken format, one
+// base64-encoded token and its rank per line in rank order. Special tokens are not part of
+// the format.
+func (c *Codec) WriteTiktoken(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	for _, token := range rankedTokens(c.MergeableRanks) {
+		bw.WriteString(base64.StdEncoding.EncodeToString([]byte(token)))
+		bw.WriteByte(' ')
+		bw.WriteString(strconv.FormatUint(uint64(c.MergeableRanks[token]), 10))
+		bw.WriteByte('\n')
+	}
+
+	return bw.Flush()
+}
+
+// WriteGPT2Files writes the mergeable ranks of the codec to vocab and encoder in the GPT-2
+// vocab.bpe and encoder.json formats, which CovertVocabBPEAndEncoderJSONToMergeableBPERanks reads.
+// The format requires the single byte tokens to have the ranks of the GPT-2 byte order and the
+// merged tokens to follow with consecutive ranks. Of the special tokens only <|endoftext|> and
+// <|startoftext|> are written.
+func (c *Codec) WriteGPT2Files(vocab, encoder io.Writer) error {
+	tokens := rankedTokens(c.MergeableRanks)
+
+	for i, token := range tokens {
+		if rank := c.MergeableRanks[token]; rank != uint(i) {
+			return fmt.Errorf("ranks are not consecutive: missing rank %d", i)
+		}
+
+		if i < 256 && token != string([]byte{byteLevelOrder[i]}) {
+			return fmt.Errorf("rank %d is not byte %#02x of the GPT-2 byte order", i, byteLevelOrder[i])
+		}
+	}
+
+	merges, err := bpeMerges(c.MergeableRanks, tokens)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(vocab)
+	bw.WriteString("#version: 0.2\n")
+
+	for _, merge := range merges {
+		bw.WriteString(encodeByteLevel(merge[0]))
+		bw.WriteByte(' ')
+		bw.WriteString(encodeByteLevel(merge[1]))
+		bw.WriteByte('\n')
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	keys, ids := vocabEntries(tokens, c.MergeableRanks)
+
+	for _, special := range []string{EndOfText, StartOfText} {
+		if id, ok := c.SpecialTokens[special]; ok {
+			keys = append(keys, special)
+			ids = append(ids, id)
+		}
+	}
+
+	return writeVocabJSON(encoder, keys, ids)
+}
+
+type tokenizerJSONOut struct {
+	Version       string              `json:"version"`
+	Truncation    json.RawMessage     `json:"truncation"`
+	Padding       json.RawMessage     `json:"padding"`
+	AddedTokens   []addedTokenJSONOut `json:"added_tokens"`
+	Normalizer    *normalizerJSONOut  `json:"normalizer"`
+	PreTokenizer  preTokenizerJSONOut `json:"pre_tokenizer"`
+	PostProcessor byteLevelJSONOut    `json:"post_processor"`
+	Decoder       byteLevelJSONOut    `json:"decoder"`
+	Model         bpeModelJSONOut     `json:"model"`
+}
+
+type addedTokenJSONOut struct {
+	ID         uint   `json:"id"`
+	Content    string `json:"content"`
+	SingleWord bool   `json:"single_word"`
+	LStrip     bool   `json:"lstrip"`
+	RStrip     bool   `json:"rstrip"`
+	Normalized bool   `json:"normalized"`
+	Special    bool   `json:"special"`
+}
+
+type normalizerJSONOut struct {
+	Type string `json:"type"`
+}
+
+type preTokenizerJSONOut struct {
+	Type          string        `json:"type"`
+	PreTokenizers []interface{} `json:"pretokenizers"`
+}
+
+type splitJSONOut struct {
+	Type     string           `json:"type"`
+	Pattern  splitPatternJSON `json:"pattern"`
+	Behavior string           `json:"behavior"`
+	Invert   bool             `json:"invert"`
+}
+
+type byteLevelJSONOut struct {
+	Type           string `json:"type"`
+	AddPrefixSpace bool   `json:"add_prefix_space"`
+	TrimOffsets    bool   `json:"trim_offsets"`
+	UseRegex       bool   `json:"use_regex"`
+}
+
+type bpeModelJSONOut struct {
+	Type                    string          `json:"type"`
+	Dropout                 *float64        `json:"dropout"`
+	UnkToken                *string         `json:"unk_token"`
+	ContinuingSubwordPrefix *string         `json:"continuing_subword_prefix"`
+	EndOfWordSuffix         *string         `json:"end_of_word_suffix"`
+	FuseUnk                 bool            `json:"fuse_unk"`
+	ByteFallback            bool            `json:"byte_fallback"`
+	IgnoreMerges            bool            `json:"ignore_merges"`
+	Vocab                   json.RawMessage `json:"vocab"`
+	Merges                  []string        `json:"merges"`
+}
+
+// WriteTokenizerJSON writes the codec to w as a Hugging Face tokenizer.json file of a byte-level
+// BPE model, which ConvertTokenizerJSONToCodec reads. The pattern becomes a regex Split
+// pre-tokenizer and the special tokens become added tokens.
+func (c *Codec) WriteTokenizerJSON(w io.Writer) error {
+	tokens := rankedTokens(c.MergeableRanks)
+
+	merges, err := bpeMerges(c.MergeableRanks, tokens)
+	if err != nil {
+		return err
+	}
+
+	byteLevelMerges := make([]string, len(merges))
+	for i, merge := range merges {
+		byteLevelMerges[i] = encodeByteLevel(merge[0]) + " " + encodeByteLevel(merge[1])
+	}
+
+	keys, ids := vocabEntries(tokens, c.MergeableRanks)
+
+	var vocab bytes.Buffer
+	if err := writeVocabJSON(&vocab, keys, ids); err != nil {
+		return err
+	}
+
+	var normalizer *normalizerJSONOut
+	if c.Normalization != "" {
+		normalizer = &normalizerJSONOut{Type: c.Normalization}
+	}
+
+	addedTokens := make([]addedTokenJSONOut, 0, len(c.SpecialTokens))
+	for content, id := range c.SpecialTokens {
+		addedTokens = append(addedTokens, addedTokenJSONOut{ID: id, Content: content, Special: true})
+	}
+
+	sort.Slice(addedTokens, func(i, j int) bool {
+		return addedTokens[i].ID < addedTokens[j].ID
+	})
+
+	patStr := c.PatStr
+
+	out := tokenizerJSONOut{
+		Version:     "1.0",
+		Truncation:  json.RawMessage("null"),
+		Padding:     json.RawMessage("null"),
+		AddedTokens: addedTokens,
+		Normalizer:  normalizer,
+		PreTokenizer: preTokenizerJSONOut{
+			Type: "Sequence",
+			PreTokenizers: []interface{}{
+				splitJSONOut{Type: "Split", Pattern: splitPatternJSON{Regex: &patStr}, Behavior: "Isolated"},
+				byteLevelJSONOut{Type: "ByteLevel", TrimOffsets: true},
+			},
+		},
+		PostProcessor: byteLevelJSONOut{Type: "ByteLevel", TrimOffsets: true, UseRegex: true},
+		Decoder:       byteLevelJSONOut{Type: "ByteLevel", TrimOffsets: true, UseRegex: true},
+		Model: bpeModelJSONOut{
+			Type:         "BPE",
+			IgnoreMerges: true,
+			Vocab:        vocab.Bytes(),
+			Merges:       byteLevelMerges,
+		},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(out)
+}
+
+// rankedTokens returns the tokens of the mergeable ranks sorted by rank.
+func rankedTokens(ranks map[string]uint) []string {
+	tokens := make([]string, 0, len(ranks))
+	for token := range ranks {
+		tokens = append(tokens, token)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return ranks[tokens[i]] < ranks[tokens[j]]
+	})
+
+	return tokens
+}
+
+// bpeMerges returns the merges of the multi-byte tokens in the given rank order. The merge of a
+// token is the pair of parts that remains after merging its bytes with all lower ranked tokens.
+func bpeMerges(ranks map[string]uint, tokens []string) ([][2]string, error) {
+	merges := make([][2]string, 0, len(tokens))
+
+	for _, token := range tokens {
+		if len(token) < 2 {
+			continue
+		}
+
+		parts := mergeParts(token, ranks, ranks[token])
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("token %q cannot be built from a merge of lower ranked tokens", token)
+		}
+
+		merges = append(merges, [2]string{parts[0], parts[1]})
+	}
+
+	return merges, nil
+}
+
+// mergeParts splits the token into bytes and merges the adjacent parts with the lowest rank
+// below maxRank until no more merges apply.
+func mergeParts(token string, ranks map[string]uint, maxRank uint) []string {
+	parts := make([]string, len(token))
+	for i := range parts {
+		parts[i] = token[i : i+1]
+	}
+
+	for len(parts) > 1 {
+		minIndex := -1
+		minRank := maxRank
+
+		for i := 0; i < len(parts)-1; i++ {
+			if rank, ok := ranks[parts[i]+parts[i+1]]; ok && rank < minRank {
+				minIndex, minRank = i, rank
+			}
+		}
+
+		if minIndex < 0 {
+			break
+		}
+
+		parts[minIndex] += parts[minIndex+1]
+		parts = append(parts[:minIndex+1], parts[minIndex+2:]...)
+	}
+
+	return parts
+}
+
+// vocabEntries returns the byte-level representations of the tokens and their ids.
+func vocabEntries(tokens []string, ranks map[string]uint) ([]string, []uint) {
+	keys := make([]string, len(tokens))
+	ids := make([]uint, len(tokens))
+
+	for i, token := range tokens {
+		keys[i] = encodeByteLevel(token)
+		ids[i] = ranks[token]
+	}
+
+	return keys, ids
+}
+
+// writeVocabJSON writes a JSON object that maps the keys to their ids in the given order.
+func writeVocabJSON(w io.Writer, keys []string, ids []uint) error {
+	var key bytes.Buffer
+
+	enc := json.NewEncoder(&key)
+	enc.SetEscapeHTML(false)
+
+	bw := bufio.NewWriter(w)
+	bw.WriteByte('{')
+
+	for i := range keys {
+		if i > 0 {
+			bw.WriteString(", ")
+		}
+
+		key.Reset()
+
+		if err := enc.Encode(keys[i]); err != nil {
+			return err
+		}
+
+		bw.Write(bytes.TrimSuffix(key.Bytes(), []byte("\n")))
+		bw.WriteString(": ")
+		bw.WriteString(strconv.FormatUint(uint64(ids[i]), 10))
+	}
+
+	bw.WriteByte('}')
+
+	return bw.Flush()
+}
@@ -0,0 +1,119 @@
+package tiktoken
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTiktoken(t *testing.T) {
+	files, err := filepath.Glob("resource/*.tiktoken")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		file := file
+
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			expected, err := os.ReadFile(file)
+			require.NoError(t, err)
+
+			codec, err := LoadCodecFromFile(file, r50kPatStr, nil)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, codec.WriteTiktoken(&buf))
+			assert.Equal(t, expected, buf.Bytes())
+
+			ranks, err := ConvertToMergeableBPERanks(&buf)
+			require.NoError(t, err)
+			assert.Equal(t, codec.MergeableRanks, ranks)
+		})
+	}
+}
+
+func TestWriteGPT2Files(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		codec, err := LoadCodecFromFile("resource/r50k_base.tiktoken", r50kPatStr, map[string]uint{EndOfText: 50256})
+		require.NoError(t, err)
+
+		var vocab, encoder bytes.Buffer
+		require.NoError(t, codec.WriteGPT2Files(&vocab, &encoder))
+		assert.True(t, strings.HasPrefix(vocab.String(), "#version: 0.2\nĠ t\nĠ a\n"))
+		assert.True(t, strings.HasPrefix(encoder.String(), `{"!": 0, "\"": 1, "#": 2,`))
+		assert.True(t, strings.HasSuffix(encoder.String(), `"<|endoftext|>": 50256}`))
+
+		ranks, err := CovertVocabBPEAndEncoderJSONToMergeableBPERanks(&vocab, &encoder)
+		require.NoError(t, err)
+		assert.Equal(t, codec.MergeableRanks, ranks)
+	})
+
+	t.Run("unsupported ranks", func(t *testing.T) {
+		ranks := make(map[string]uint, 256)
+		for i, b := range byteLevelOrder {
+			ranks[string([]byte{b})] = uint(i)
+		}
+
+		ranks["ab"] = 300
+
+		var vocab, encoder bytes.Buffer
+		assert.EqualError(t, (&Codec{MergeableRanks: ranks}).WriteGPT2Files(&vocab, &encoder), "ranks are not consecutive: missing rank 256")
+
+		ranks["ab"] = 256
+		ranks["a"], ranks["b"] = ranks["b"], ranks["a"]
+		assert.EqualError(t, (&Codec{MergeableRanks: ranks}).WriteGPT2Files(&vocab, &encoder), "rank 64 is not byte 0x61 of the GPT-2 byte order")
+	})
+}
+
+func TestWriteTokenizerJSON(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		specials := map[string]uint{EndOfText: 100257, FimPrefix: 100258, EndOfPrompt: 100276}
+
+		codec, err := LoadCodecFromFile("resource/cl100k_base.tiktoken", cl100kPatStr, specials, WithNormalization(NFC))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, codec.WriteTokenizerJSON(&buf))
+		assert.Contains(t, buf.String(), `"content": "<|endoftext|>"`)
+
+		imported, err := ConvertTokenizerJSONToCodec(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, codec.MergeableRanks, imported.MergeableRanks)
+		assert.Equal(t, codec.SpecialTokens, imported.SpecialTokens)
+		assert.Equal(t, cl100kPatStr, imported.PatStr)
+		assert.Equal(t, NFC, imported.Normalization)
+	})
+
+	t.Run("golden", func(t *testing.T) {
+		ranks := make(map[string]uint, 259)
+		for i, b := range byteLevelOrder {
+			ranks[string([]byte{b})] = uint(i)
+		}
+
+		ranks[" t"], ranks["he"], ranks[" the"] = 256, 257, 258
+
+		codec := &Codec{
+			PatStr:         `\s+|\S+`,
+			MergeableRanks: ranks,
+			SpecialTokens:  map[string]uint{EndOfText: 259},
+			Normalization:  NFC,
+		}
+
+		expected, err := os.ReadFile("testdata/tokenizer.json")
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, codec.WriteTokenizerJSON(&buf))
+		assert.Equal(t, string(expected), buf.String())
+	})
+
+	t.Run("unreachable token", func(t *testing.T) {
+		codec := &Codec{MergeableRanks: map[string]uint{"a": 0, "b": 1, "c": 2, "abc": 3}}
+		assert.EqualError(t, codec.WriteTokenizerJSON(&bytes.Buffer{}), `token "abc" cannot be built from a merge of lower ranked tokens`)
+	})
+}
@@ -1,3 +1,5 @@
+//go:build !tiktoken_minimal || tiktoken_gpt2
+
 package tiktoken
 
 import (
@@ -11,6 +13,10 @@ var gpt2Vocab string
 //go:embed resource/gpt2/encoder.json
 var gpt2Encode string
 
+func init() {
+	MustRegisterEncoding(GPT2, NewGPT2)
+}
+
 // NewGPT2 creates a new Codec instance for the GPT-2 tokenization scheme.
 // It loads the mergeable ranks from the embedded gpt2Vocab and gpt2Encode resources.
 // The function returns a pointer to the Codec or an error if any.
@@ -23,7 +29,7 @@ func NewGPT2() (*Codec, error) {
 	return &Codec{
 		Name:           "gpt2",
 		ExplicitNVocab: 50257,
-		PatStr:         `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`,
+		PatStr:         r50kPatStr,
 		MergeableRanks: ranks,
 		SpecialTokens: map[string]uint{
 			EndOfText: 50256,
@@ -0,0 +1,291 @@
+package tiktoken
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type tokenizerJSON struct {
+	AddedTokens  []addedTokenJSON  `json:"added_tokens"`
+	Normalizer   *normalizerJSON   `json:"normalizer"`
+	PreTokenizer *preTokenizerJSON `json:"pre_tokenizer"`
+	Model        bpeModelJSON      `json:"model"`
+}
+
+type addedTokenJSON struct {
+	ID      uint   `json:"id"`
+	Content string `json:"content"`
+	Special bool   `json:"special"`
+}
+
+type normalizerJSON struct {
+	Type        string           `json:"type"`
+	Normalizers []normalizerJSON `json:"normalizers"`
+}
+
+type preTokenizerJSON struct {
+	Type           string             `json:"type"`
+	AddPrefixSpace bool               `json:"add_prefix_space"`
+	UseRegex       *bool              `json:"use_regex"`
+	Pattern        *splitPatternJSON  `json:"pattern"`
+	Behavior       string             `json:"behavior"`
+	Invert         bool               `json:"invert"`
+	PreTokenizers  []preTokenizerJSON `json:"pretokenizers"`
+}
+
+type splitPatternJSON struct {
+	Regex *string `json:"Regex"`
+}
+
+type bpeModelJSON struct {
+	Type                    string            `json:"type"`
+	Dropout                 *float64          `json:"dropout"`
+	ContinuingSubwordPrefix *string           `json:"continuing_subword_prefix"`
+	EndOfWordSuffix         *string           `json:"end_of_word_suffix"`
+	ByteFallback            bool              `json:"byte_fallback"`
+	Vocab                   map[string]uint   `json:"vocab"`
+	Merges                  []json.RawMessage `json:"merges"`
+}
+
+// ConvertTokenizerJSONToCodec converts a Hugging Face tokenizer.json file of a byte-level BPE
+// model to a Codec. The vocabulary becomes the mergeable ranks, the pre-tokenizer regex the
+// pattern and the added tokens the special tokens. The Name of the returned Codec is empty.
+//
+// Only tokenizers that behave like tiktoken are supported: a BPE model whose merges are in rank
+// order, an optional unicode normalizer, and a ByteLevel pre-tokenizer, optionally preceded by
+// a single isolating regex Split. Other configurations are rejected with an error, as are added
+// tokens that are not special, which tiktoken cannot encode like Hugging Face. Post-processors
+// and decoders are ignored, so tokens such as a BOS token added by a template are not encoded.
+func ConvertTokenizerJSONToCodec(tokenizerJSONReader io.Reader) (*Codec, error) {
+	var tokenizer tokenizerJSON
+	if err := json.NewDecoder(tokenizerJSONReader).Decode(&tokenizer); err != nil {
+		return nil, err
+	}
+
+	normalization, err := tokenizer.Normalizer.form()
+	if err != nil {
+		return nil, err
+	}
+
+	patStr, err := tokenizer.PreTokenizer.pattern()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tokenizer.Model.validate(); err != nil {
+		return nil, err
+	}
+
+	specialTokens := make(map[string]uint, len(tokenizer.AddedTokens))
+	addedIDs := make(map[uint]string, len(tokenizer.AddedTokens))
+
+	for _, token := range tokenizer.AddedTokens {
+		if !token.Special {
+			return nil, fmt.Errorf("unsupported added token %q: not special", token.Content)
+		}
+
+		specialTokens[token.Content] = token.ID
+		addedIDs[token.ID] = token.Content
+	}
+
+	ranks := make(map[string]uint, len(tokenizer.Model.Vocab))
+
+	for token, id := range tokenizer.Model.Vocab {
+		if content, ok := addedIDs[id]; ok {
+			if content != token {
+				return nil, fmt.Errorf("added token %q reuses id %d of %q", content, id, token)
+			}
+
+			continue
+		}
+
+		key, err := decodeByteLevel(token)
+		if err != nil {
+			return nil, err
+		}
+
+		ranks[key] = id
+	}
+
+	for b := 0; b < 256; b++ {
+		if _, ok := ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("vocabulary has no token for byte %#02x", b)
+		}
+	}
+
+	if err := tokenizer.Model.checkMerges(); err != nil {
+		return nil, err
+	}
+
+	return &Codec{
+		PatStr:         patStr,
+		MergeableRanks: ranks,
+		SpecialTokens:  specialTokens,
+		Normalization:  normalization,
+	}, nil
+}
+
+// form returns the unicode normalization form of the normalizer.
+func (n *normalizerJSON) form() (string, error) {
+	if n == nil {
+		return "", nil
+	}
+
+	switch n.Type {
+	case NFC, NFD, NFKC, NFKD:
+		return n.Type, nil
+	case "Sequence":
+		switch len(n.Normalizers) {
+		case 0:
+			return "", nil
+		case 1:
+			return n.Normalizers[0].form()
+		}
+
+		return "", errors.New("unsupported normalizer: sequence of more than one normalizer")
+	}
+
+	return "", fmt.Errorf("unsupported normalizer: %s", n.Type)
+}
+
+// pattern returns the pre-tokenization pattern of the pre-tokenizer.
+func (p *preTokenizerJSON) pattern() (string, error) {
+	if p == nil {
+		return "", errors.New("unsupported pre-tokenizer: none")
+	}
+
+	switch p.Type {
+	case "ByteLevel":
+		if err := p.validateByteLevel(); err != nil {
+			return "", err
+		}
+
+		if p.UseRegex != nil && !*p.UseRegex {
+			return "", errors.New("unsupported pre-tokenizer: ByteLevel without regex")
+		}
+
+		return r50kPatStr, nil
+	case "Sequence":
+		if len(p.PreTokenizers) != 2 || p.PreTokenizers[0].Type != "Split" || p.PreTokenizers[1].Type != "ByteLevel" {
+			types := make([]string, len(p.PreTokenizers))
+			for i, pre := range p.PreTokenizers {
+				types[i] = pre.Type
+			}
+
+			return "", fmt.Errorf("unsupported pre-tokenizer: sequence of [%s], expected [Split, ByteLevel]", strings.Join(types, ", "))
+		}
+
+		split, byteLevel := p.PreTokenizers[0], p.PreTokenizers[1]
+
+		if err := byteLevel.validateByteLevel(); err != nil {
+			return "", err
+		}
+
+		if byteLevel.UseRegex == nil || *byteLevel.UseRegex {
+			return "", errors.New("unsupported pre-tokenizer: ByteLevel with regex after Split")
+		}
+
+		if split.Pattern == nil || split.Pattern.Regex == nil {
+			return "", errors.New("unsupported pre-tokenizer: Split without regex pattern")
+		}
+
+		if split.Behavior != "Isolated" || split.Invert {
+			return "", fmt.Errorf("unsupported pre-tokenizer: Split with behavior %s", split.Behavior)
+		}
+
+		return *split.Pattern.Regex, nil
+	}
+
+	return "", fmt.Errorf("unsupported pre-tokenizer: %s", p.Type)
+}
+
+// validateByteLevel checks the options of a ByteLevel pre-tokenizer.
+func (p *preTokenizerJSON) validateByteLevel() error {
+	if p.AddPrefixSpace {
+		return errors.New("unsupported pre-tokenizer: ByteLevel with add_prefix_space")
+	}
+
+	return nil
+}
+
+// validate checks that the model is a byte-level BPE model.
+func (m *bpeModelJSON) validate() error {
+	if m.Type != "" && m.Type != "BPE" {
+		return fmt.Errorf("unsupported model: %s", m.Type)
+	}
+
+	if m.Dropout != nil && *m.Dropout != 0 {
+		return errors.New("unsupported model: BPE with dropout")
+	}
+
+	if m.ContinuingSubwordPrefix != nil && *m.ContinuingSubwordPrefix != "" {
+		return errors.New("unsupported model: BPE with continuing_subword_prefix")
+	}
+
+	if m.EndOfWordSuffix != nil && *m.EndOfWordSuffix != "" {
+		return errors.New("unsupported model: BPE with end_of_word_suffix")
+	}
+
+	if m.ByteFallback {
+		return errors.New("unsupported model: BPE with byte_fallback")
+	}
+
+	if len(m.Vocab) == 0 {
+		return errors.New("unsupported model: empty vocabulary")
+	}
+
+	return nil
+}
+
+// checkMerges verifies that the merges produce tokens of increasing rank, which makes
+// merging by rank equivalent to merging by merge priority.
+func (m *bpeModelJSON) checkMerges() error {
+	var previous uint
+
+	for i, raw := range m.Merges {
+		first, second, err := parseMerge(raw)
+		if err != nil {
+			return err
+		}
+
+		id, ok := m.Vocab[first+second]
+		if !ok {
+			return fmt.Errorf("merge %q %q produces a token that is not in the vocabulary", first, second)
+		}
+
+		if i > 0 && id <= previous {
+			return fmt.Errorf("merge %q %q is not in rank order", first, second)
+		}
+
+		previous = id
+	}
+
+	return nil
+}
+
+// parseMerge parses a merge in the "first second" or ["first", "second"] format.
+func parseMerge(raw json.RawMessage) (string, string, error) {
+	var pair []string
+	if err := json.Unmarshal(raw, &pair); err == nil {
+		if len(pair) != 2 {
+			return "", "", fmt.Errorf("invalid merge: %s", raw)
+		}
+
+		return pair[0], pair[1], nil
+	}
+
+	var merge string
+	if err := json.Unmarshal(raw, &merge); err != nil {
+		return "", "", fmt.Errorf("invalid merge: %s", raw)
+	}
+
+	first, second, ok := strings.Cut(merge, " ")
+	if !ok || strings.Contains(second, " ") {
+		return "", "", fmt.Errorf("invalid merge: %q", merge)
+	}
+
+	return first, second, nil
+}
@@ -0,0 +1,241 @@
+package tiktoken
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// byteLevelVocab returns a byte-level vocabulary of the single byte tokens and the given
+// merged tokens, which are ranked in order.
+func byteLevelVocab(merged ...string) map[string]uint {
+	vocab := make(map[string]uint, 256+len(merged))
+	for i, b := range byteLevelOrder {
+		vocab[string(byteLevelRunes[b])] = uint(i)
+	}
+
+	for i, token := range merged {
+		vocab[token] = uint(256 + i)
+	}
+
+	return vocab
+}
+
+// gpt2TokenizerJSON builds a tokenizer.json file from the GPT-2 vocab.bpe and encoder.json resources.
+func gpt2TokenizerJSON(t *testing.T) []byte {
+	t.Helper()
+
+	vocabBPE, err := os.ReadFile("resource/gpt2/vocab.bpe")
+	require.NoError(t, err)
+
+	encoderJSON, err := os.ReadFile("resource/gpt2/encoder.json")
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(vocabBPE)), "\n")[1:]
+
+	data, err := json.Marshal(map[string]interface{}{
+		"added_tokens":  []map[string]interface{}{{"id": 50256, "content": EndOfText, "special": true}},
+		"normalizer":    nil,
+		"pre_tokenizer": map[string]interface{}{"type": "ByteLevel", "add_prefix_space": false, "trim_offsets": true, "use_regex": true},
+		"model": map[string]interface{}{
+			"type":    "BPE",
+			"dropout": nil,
+			"vocab":   json.RawMessage(encoderJSON),
+			"merges":  lines,
+		},
+	})
+	require.NoError(t, err)
+
+	return data
+}
+
+func TestConvertTokenizerJSONToCodec(t *testing.T) {
+	t.Run("gpt2", func(t *testing.T) {
+		codec, err := ConvertTokenizerJSONToCodec(bytes.NewReader(gpt2TokenizerJSON(t)))
+		require.NoError(t, err)
+
+		vocabBPE, err := os.Open("resource/gpt2/vocab.bpe")
+		require.NoError(t, err)
+
+		defer vocabBPE.Close()
+
+		encoderJSON, err := os.Open("resource/gpt2/encoder.json")
+		require.NoError(t, err)
+
+		defer encoderJSON.Close()
+
+		expected, err := CovertVocabBPEAndEncoderJSONToMergeableBPERanks(vocabBPE, encoderJSON)
+		require.NoError(t, err)
+
+		assert.Equal(t, expected, codec.MergeableRanks)
+		assert.Equal(t, r50kPatStr, codec.PatStr)
+		assert.Equal(t, map[string]uint{EndOfText: 50256}, codec.SpecialTokens)
+		assert.Empty(t, codec.Normalization)
+	})
+
+	t.Run("split pre-tokenizer", func(t *testing.T) {
+		data, err := json.Marshal(map[string]interface{}{
+			"added_tokens": []map[string]interface{}{{"id": 258, "content": "<|eot|>", "special": true}},
+			"normalizer":   map[string]interface{}{"type": "Sequence", "normalizers": []interface{}{map[string]interface{}{"type": "NFC"}}},
+			"pre_tokenizer": map[string]interface{}{
+				"type": "Sequence",
+				"pretokenizers": []interface{}{
+					map[string]interface{}{"type": "Split", "pattern": map[string]interface{}{"Regex": cl100kPatStr}, "behavior": "Isolated", "invert": false},
+					map[string]interface{}{"type": "ByteLevel", "add_prefix_space": false, "use_regex": false},
+				},
+			},
+			"model": map[string]interface{}{
+				"type":          "BPE",
+				"ignore_merges": true,
+				"vocab":         byteLevelVocab("ĠÃ", "ĠÃ©"),
+				"merges":        [][]string{{"Ġ", "Ã"}, {"ĠÃ", "©"}},
+			},
+		})
+		require.NoError(t, err)
+
+		codec, err := ConvertTokenizerJSONToCodec(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, cl100kPatStr, codec.PatStr)
+		assert.Equal(t, NFC, codec.Normalization)
+		assert.Equal(t, map[string]uint{"<|eot|>": 258}, codec.SpecialTokens)
+		assert.Len(t, codec.MergeableRanks, 258)
+		assert.Equal(t, uint(257), codec.MergeableRanks[" é"])
+
+		encoding, err := NewEncoding(codec)
+		require.NoError(t, err)
+
+		ids, err := encoding.EncodeIDs("a é<|eot|>", WithAllSpecialAllowed())
+		require.NoError(t, err)
+		assert.Equal(t, []uint{codec.MergeableRanks["a"], 257, 258}, ids)
+	})
+}
+
+func TestConvertTokenizerJSONToCodecErrors(t *testing.T) {
+	byteLevel := map[string]interface{}{"type": "ByteLevel", "add_prefix_space": false}
+
+	tests := []struct {
+		name      string
+		tokenizer map[string]interface{}
+		expected  string
+	}{
+		{
+			name:      "unsupported normalizer",
+			tokenizer: map[string]interface{}{"normalizer": map[string]interface{}{"type": "Lowercase"}},
+			expected:  "unsupported normalizer: Lowercase",
+		},
+		{
+			name: "normalizer sequence",
+			tokenizer: map[string]interface{}{"normalizer": map[string]interface{}{"type": "Sequence", "normalizers": []interface{}{
+				map[string]interface{}{"type": "NFC"}, map[string]interface{}{"type": "Lowercase"},
+			}}},
+			expected: "unsupported normalizer: sequence of more than one normalizer",
+		},
+		{
+			name:      "missing pre-tokenizer",
+			tokenizer: map[string]interface{}{},
+			expected:  "unsupported pre-tokenizer: none",
+		},
+		{
+			name:      "unsupported pre-tokenizer",
+			tokenizer: map[string]interface{}{"pre_tokenizer": map[string]interface{}{"type": "Metaspace"}},
+			expected:  "unsupported pre-tokenizer: Metaspace",
+		},
+		{
+			name:      "add prefix space",
+			tokenizer: map[string]interface{}{"pre_tokenizer": map[string]interface{}{"type": "ByteLevel", "add_prefix_space": true}},
+			expected:  "unsupported pre-tokenizer: ByteLevel with add_prefix_space",
+		},
+		{
+			name: "pre-tokenizer sequence",
+			tokenizer: map[string]interface{}{"pre_tokenizer": map[string]interface{}{"type": "Sequence", "pretokenizers": []interface{}{
+				map[string]interface{}{"type": "Digits"}, byteLevel,
+			}}},
+			expected: "unsupported pre-tokenizer: sequence of [Digits, ByteLevel], expected [Split, ByteLevel]",
+		},
+		{
+			name: "split behavior",
+			tokenizer: map[string]interface{}{"pre_tokenizer": map[string]interface{}{"type": "Sequence", "pretokenizers": []interface{}{
+				map[string]interface{}{"type": "Split", "pattern": map[string]interface{}{"Regex": `\s+`}, "behavior": "Removed"},
+				map[string]interface{}{"type": "ByteLevel", "use_regex": false},
+			}}},
+			expected: "unsupported pre-tokenizer: Split with behavior Removed",
+		},
+		{
+			name: "split string pattern",
+			tokenizer: map[string]interface{}{"pre_tokenizer": map[string]interface{}{"type": "Sequence", "pretokenizers": []interface{}{
+				map[string]interface{}{"type": "Split", "pattern": map[string]interface{}{"String": " "}, "behavior": "Isolated"},
+				map[string]interface{}{"type": "ByteLevel", "use_regex": false},
+			}}},
+			expected: "unsupported pre-tokenizer: Split without regex pattern",
+		},
+		{
+			name:      "unsupported model",
+			tokenizer: map[string]interface{}{"pre_tokenizer": byteLevel, "model": map[string]interface{}{"type": "WordPiece"}},
+			expected:  "unsupported model: WordPiece",
+		},
+		{
+			name:      "byte fallback",
+			tokenizer: map[string]interface{}{"pre_tokenizer": byteLevel, "model": map[string]interface{}{"type": "BPE", "byte_fallback": true}},
+			expected:  "unsupported model: BPE with byte_fallback",
+		},
+		{
+			name:      "missing byte",
+			tokenizer: map[string]interface{}{"pre_tokenizer": byteLevel, "model": map[string]interface{}{"vocab": map[string]uint{"a": 0}}},
+			expected:  "vocabulary has no token for byte 0x00",
+		},
+		{
+			name:      "invalid token",
+			tokenizer: map[string]interface{}{"pre_tokenizer": byteLevel, "model": map[string]interface{}{"vocab": map[string]uint{"a b": 0}}},
+			expected:  `invalid byte-level token "a b"`,
+		},
+		{
+			name: "unknown merge result",
+			tokenizer: map[string]interface{}{"pre_tokenizer": byteLevel, "model": map[string]interface{}{
+				"vocab": byteLevelVocab(), "merges": []string{"a b"},
+			}},
+			expected: `merge "a" "b" produces a token that is not in the vocabulary`,
+		},
+		{
+			name: "merges out of rank order",
+			tokenizer: map[string]interface{}{"pre_tokenizer": byteLevel, "model": map[string]interface{}{
+				"vocab": byteLevelVocab("ab", "cd"), "merges": []string{"c d", "a b"},
+			}},
+			expected: `merge "a" "b" is not in rank order`,
+		},
+		{
+			name: "added token id clash",
+			tokenizer: map[string]interface{}{
+				"pre_tokenizer": byteLevel,
+				"added_tokens":  []map[string]interface{}{{"id": 256, "content": "<|end|>", "special": true}},
+				"model":         map[string]interface{}{"vocab": byteLevelVocab("ab")},
+			},
+			expected: `added token "<|end|>" reuses id 256 of "ab"`,
+		},
+		{
+			name: "non-special added token",
+			tokenizer: map[string]interface{}{
+				"pre_tokenizer": byteLevel,
+				"added_tokens":  []map[string]interface{}{{"id": 256, "content": "<|tool|>", "special": false}},
+				"model":         map[string]interface{}{"vocab": byteLevelVocab()},
+			},
+			expected: `unsupported added token "<|tool|>": not special`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.tokenizer)
+			require.NoError(t, err)
+
+			_, err = ConvertTokenizerJSONToCodec(bytes.NewReader(data))
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
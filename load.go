@@ -0,0 +1,127 @@
+package tiktoken
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"unsafe"
+)
+
+// LoadOption configures LoadCodecFromFile and LoadCodecFromFS.
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	name          string
+	expectedHash  string
+	normalization string
+}
+
+// WithCodecName sets the name of the loaded Codec. It defaults to the file name without extension.
+func WithCodecName(name string) LoadOption {
+	return func(o *loadOptions) {
+		o.name = name
+	}
+}
+
+// WithExpectedHash verifies that the hex-encoded SHA-256 hash of the rank file equals the given hash.
+func WithExpectedHash(sha256Hex string) LoadOption {
+	return func(o *loadOptions) {
+		o.expectedHash = strings.ToLower(sha256Hex)
+	}
+}
+
+// WithNormalization sets the unicode normalization form of the loaded Codec, see Codec.Normalization.
+func WithNormalization(form string) LoadOption {
+	return func(o *loadOptions) {
+		o.normalization = form
+	}
+}
+
+// LoadCodecFromFile loads a Codec from a rank file in the .tiktoken format (one base64-encoded
+// token and its rank per line) with the given pre-tokenization pattern and special tokens.
+// Rank files in the binary rank format written by WriteBinaryRanks are detected and loaded
+// as well. Where supported, they are memory-mapped and the tokens of the Codec reference the
+// mapping instead of the heap. The mapping is never released, as the tokens may outlive the
+// Codec and every Encoding created from it, so the file must not be modified afterwards.
+func LoadCodecFromFile(filename, patStr string, specialTokens map[string]uint, optFns ...LoadOption) (*Codec, error) {
+	data, release, err := mapFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+
+	codec, err := loadCodec(name, data, patStr, specialTokens, optFns)
+	if err == nil && isBinaryRanks(data) {
+		return codec, nil
+	}
+
+	// the tokens of .tiktoken files are decoded copies
+	if rerr := release(); rerr != nil && err == nil {
+		return nil, rerr
+	}
+
+	return codec, err
+}
+
+// LoadCodecFromFS is like LoadCodecFromFile but reads the rank file from the file system fsys.
+func LoadCodecFromFS(fsys fs.FS, filename, patStr string, specialTokens map[string]uint, optFns ...LoadOption) (*Codec, error) {
+	data, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+
+	return loadCodec(name, data, patStr, specialTokens, optFns)
+}
+
+// loadCodec creates a Codec from the contents of a rank file. The tokens of binary rank files
+// reference data, which must not be modified afterwards.
+func loadCodec(name string, data []byte, patStr string, specialTokens map[string]uint, optFns []LoadOption) (*Codec, error) {
+	opts := loadOptions{name: name}
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	if opts.expectedHash != "" {
+		sum := sha256.Sum256(data)
+		if hash := hex.EncodeToString(sum[:]); hash != opts.expectedHash {
+			return nil, fmt.Errorf("hash mismatch for %s: expected %s, got %s", name, opts.expectedHash, hash)
+		}
+	}
+
+	var (
+		ranks map[string]uint
+		err   error
+	)
+
+	if isBinaryRanks(data) {
+		// data is mapped or owned by the Codec, so the tokens can share it without a copy
+		ranks, err = parseBinaryRanks(unsafe.String(unsafe.SliceData(data), len(data))) //nolint:gosec // data is never modified
+	} else {
+		ranks, err = ConvertToMergeableBPERanks(bytes.NewReader(data))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", name, err)
+	}
+
+	specials := make(map[string]uint, len(specialTokens))
+	for k, v := range specialTokens {
+		specials[k] = v
+	}
+
+	return &Codec{
+		Name:           opts.name,
+		PatStr:         patStr,
+		MergeableRanks: ranks,
+		SpecialTokens:  specials,
+		Normalization:  opts.normalization,
+	}, nil
+}
@@ -0,0 +1,79 @@
+//go:build !tiktoken_minimal
+
+package tiktoken
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cl100kBaseHash = "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7"
+
+func TestLoadCodecFromFile(t *testing.T) {
+	t.Run("embedded vocabulary", func(t *testing.T) {
+		specials := map[string]uint{EndOfText: 100257}
+
+		codec, err := LoadCodecFromFile("resource/cl100k_base.tiktoken", cl100kPatStr, specials, WithExpectedHash(cl100kBaseHash))
+		require.NoError(t, err)
+		assert.Equal(t, "cl100k_base", codec.Name)
+
+		expected, err := NewCL100kBase()
+		require.NoError(t, err)
+		assert.Equal(t, expected.MergeableRanks, codec.MergeableRanks)
+
+		// the special tokens are copied
+		specials[EndOfText] = 0
+		assert.Equal(t, uint(100257), codec.SpecialTokens[EndOfText])
+	})
+
+	t.Run("custom vocabulary", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "small.tiktoken")
+		require.NoError(t, os.WriteFile(filename, []byte(smallRankFile), 0o600))
+
+		codec, err := LoadCodecFromFile(filename, `\S+|\s+`, map[string]uint{"<|end|>": 5}, WithCodecName("my_small"))
+		require.NoError(t, err)
+		assert.Equal(t, "my_small", codec.Name)
+
+		encoding, err := NewEncoding(codec)
+		require.NoError(t, err)
+
+		ids, err := encoding.EncodeIDs("abc cab<|end|>", WithAllowedSpecial("<|end|>"), WithSpecialAsText())
+		require.NoError(t, err)
+		assert.Equal(t, []uint{4}, ids[:1])
+		assert.Equal(t, uint(5), ids[len(ids)-1])
+	})
+
+	t.Run("hash mismatch", func(t *testing.T) {
+		_, err := LoadCodecFromFile("resource/p50k_base.tiktoken", r50kPatStr, nil, WithExpectedHash(cl100kBaseHash))
+		assert.ErrorContains(t, err, "hash mismatch for p50k_base")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadCodecFromFile("resource/missing.tiktoken", r50kPatStr, nil)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestLoadCodecFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"vocab/small.tiktoken":  {Data: []byte(smallRankFile)},
+		"vocab/broken.tiktoken": {Data: []byte("YQ==\n")},
+	}
+
+	codec, err := LoadCodecFromFS(fsys, "vocab/small.tiktoken", `\S+|\s+`, nil, WithNormalization(NFC))
+	require.NoError(t, err)
+	assert.Equal(t, "small", codec.Name)
+	assert.Equal(t, NFC, codec.Normalization)
+	assert.Len(t, codec.MergeableRanks, 5)
+
+	_, err = LoadCodecFromFS(fsys, "vocab/broken.tiktoken", `\S+|\s+`, nil)
+	assert.ErrorContains(t, err, "invalid bpe line")
+
+	_, err = LoadCodecFromFS(fsys, "vocab/missing.tiktoken", `\S+|\s+`, nil)
+	assert.Error(t, err)
+}
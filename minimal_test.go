@@ -0,0 +1,52 @@
+//go:build tiktoken_minimal
+
+package tiktoken
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinimalBuild(t *testing.T) {
+	registered := map[string]bool{}
+	for _, name := range ListEncodings() {
+		registered[name] = true
+	}
+
+	for _, name := range []string{O200kBase, CL100kBase, P50kBase, P50kEdit, R50kBase, GPT2, Claude} {
+		name := name
+
+		t.Run(name, func(t *testing.T) {
+			encoding, err := NewEncodingByName(name)
+			if !registered[name] {
+				assert.EqualError(t, err, "unknown encoding: "+name)
+				return
+			}
+
+			require.NoError(t, err)
+
+			ids, err := encoding.EncodeIDs("hello world")
+			require.NoError(t, err)
+			assert.Equal(t, "hello world", string(encoding.Decode(ids)))
+		})
+	}
+}
+
+func TestMinimalBuildCustomEncoding(t *testing.T) {
+	fsys := fstest.MapFS{
+		"small.tiktoken": {Data: []byte(smallRankFile)},
+	}
+
+	codec, err := LoadCodecFromFS(fsys, "small.tiktoken", `\S+|\s+`, nil)
+	require.NoError(t, err)
+
+	encoding, err := NewEncoding(codec)
+	require.NoError(t, err)
+
+	ids, err := encoding.EncodeIDs("abc")
+	require.NoError(t, err)
+	assert.Equal(t, []uint{4}, ids)
+}
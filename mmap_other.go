@@ -0,0 +1,15 @@
+//go:build !unix
+
+package tiktoken
+
+import "os"
+
+// mapFile reads the file into memory on platforms without mmap support.
+func mapFile(filename string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, func() error { return nil }, nil
+}
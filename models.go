@@ -0,0 +1,276 @@
+package tiktoken
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ModelInfo describes a model and its token limits.
+type ModelInfo struct {
+	// Name is the model name, or the name prefix of a model family.
+	Name string
+	// Encoding is the name of the encoding of the model.
+	Encoding string
+	// ContextWindow is the maximum number of prompt and completion tokens, or 0 if unknown.
+	ContextWindow int
+	// MaxOutputTokens is the maximum number of completion tokens, or 0 if they are only limited
+	// by the context window.
+	MaxOutputTokens int
+	// Chat reports whether the model takes chat messages instead of a plain prompt.
+	Chat bool
+	// ImageBaseTokens is the number of tokens of every image input, and the total of low detail
+	// images, or 0 if the model does not accept images.
+	ImageBaseTokens int
+	// ImageTileTokens is the number of tokens of every 512px tile of high detail images.
+	ImageTileTokens int
+}
+
+// builtinModels are the models known by their exact name.
+var builtinModels = []ModelInfo{
+	// chat
+	{Name: "o1", Encoding: O200kBase, ContextWindow: 200000, MaxOutputTokens: 100000, Chat: true},
+	{Name: "o1-preview", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 32768, Chat: true},
+	{Name: "o1-mini", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 65536, Chat: true},
+	{Name: "chatgpt-4o-latest", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 16384, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},
+	{Name: "gpt-4o", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 16384, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},
+	{Name: "gpt-4o-2024-05-13", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 4096, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},
+	{Name: "gpt-4o-mini", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 16384, Chat: true, ImageBaseTokens: 2833, ImageTileTokens: 5667},
+	{Name: "gpt-4", Encoding: CL100kBase, ContextWindow: 8192, MaxOutputTokens: 8192, Chat: true},
+	{Name: "gpt-4-turbo", Encoding: CL100kBase, ContextWindow: 128000, MaxOutputTokens: 4096, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},
+	{Name: "gpt-4-turbo-2024-04-09", Encoding: CL100kBase, ContextWindow: 128000, MaxOutputTokens: 4096, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},
+	{Name: "gpt-4-turbo-preview", Encoding: CL100kBase, ContextWindow: 128000, MaxOutputTokens: 4096, Chat: true},
+	{Name: "gpt-4-vision-preview", Encoding: CL100kBase, ContextWindow: 128000, MaxOutputTokens: 4096, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},
+	{Name: "gpt-4-1106-vision-preview", Encoding: CL100kBase, ContextWindow: 128000, MaxOutputTokens: 4096, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},
+	{Name: "gpt-3.5-turbo", Encoding: CL100kBase, ContextWindow: 16385, MaxOutputTokens: 4096, Chat: true},
+	{Name: "gpt-3.5-turbo-0301", Encoding: CL100kBase, ContextWindow: 4096, MaxOutputTokens: 4096, Chat: true},
+	{Name: "gpt-3.5-turbo-0613", Encoding: CL100kBase, ContextWindow: 4096, MaxOutputTokens: 4096, Chat: true},
+	{Name: "gpt-3.5-turbo-instruct", Encoding: CL100kBase, ContextWindow: 4096, MaxOutputTokens: 4096},
+	{Name: "gpt-35-turbo", Encoding: CL100kBase, ContextWindow: 16385, MaxOutputTokens: 4096, Chat: true}, // Azure deployment name
+	// text
+	{Name: "text-davinci-003", Encoding: P50kBase, ContextWindow: 4097},
+	{Name: "text-davinci-002", Encoding: P50kBase, ContextWindow: 4097},
+	{Name: "text-davinci-001", Encoding: R50kBase, ContextWindow: 2049},
+	{Name: "text-curie-001", Encoding: R50kBase, ContextWindow: 2049},
+	{Name: "text-babbage-001", Encoding: R50kBase, ContextWindow: 2049},
+	{Name: "text-ada-001", Encoding: R50kBase, ContextWindow: 2049},
+	{Name: "davinci", Encoding: R50kBase, ContextWindow: 2049},
+	{Name: "curie", Encoding: R50kBase, ContextWindow: 2049},
+	{Name: "babbage", Encoding: R50kBase, ContextWindow: 2049},
+	{Name: "ada", Encoding: R50kBase, ContextWindow: 2049},
+	{Name: "davinci-002", Encoding: CL100kBase, ContextWindow: 16384},
+	{Name: "babbage-002", Encoding: CL100kBase, ContextWindow: 16384},
+	// code
+	{Name: "code-davinci-002", Encoding: P50kBase, ContextWindow: 8001},
+	{Name: "code-davinci-001", Encoding: P50kBase, ContextWindow: 8001},
+	{Name: "code-cushman-002", Encoding: P50kBase, ContextWindow: 2048},
+	{Name: "code-cushman-001", Encoding: P50kBase, ContextWindow: 2048},
+	{Name: "davinci-codex", Encoding: P50kBase, ContextWindow: 4096},
+	{Name: "cushman-codex", Encoding: P50kBase, ContextWindow: 2048},
+	// edit
+	{Name: "text-davinci-edit-001", Encoding: P50kEdit, ContextWindow: 2049},
+	{Name: "code-davinci-edit-001", Encoding: P50kEdit, ContextWindow: 2049},
+	// embeddings
+	{Name: "text-embedding-ada-002", Encoding: CL100kBase, ContextWindow: 8191},
+	{Name: "text-embedding-3-small", Encoding: CL100kBase, ContextWindow: 8191},
+	{Name: "text-embedding-3-large", Encoding: CL100kBase, ContextWindow: 8191},
+	// old embeddings
+	{Name: "text-similarity-davinci-001", Encoding: R50kBase, ContextWindow: 2046},
+	{Name: "text-similarity-curie-001", Encoding: R50kBase, ContextWindow: 2046},
+	{Name: "text-similarity-babbage-001", Encoding: R50kBase, ContextWindow: 2046},
+	{Name: "text-similarity-ada-001", Encoding: R50kBase, ContextWindow: 2046},
+	{Name: "text-search-davinci-doc-001", Encoding: R50kBase, ContextWindow: 2046},
+	{Name: "text-search-curie-doc-001", Encoding: R50kBase, ContextWindow: 2046},
+	{Name: "text-search-babbage-doc-001", Encoding: R50kBase, ContextWindow: 2046},
+	{Name: "text-search-ada-doc-001", Encoding: R50kBase, ContextWindow: 2046},
+	{Name: "code-search-babbage-code-001", Encoding: R50kBase, ContextWindow: 2046},
+	{Name: "code-search-ada-code-001", Encoding: R50kBase, ContextWindow: 2046},
+	// open source
+	{Name: "gpt2", Encoding: GPT2, ContextWindow: 1024},
+	{Name: "gpt-2", Encoding: GPT2, ContextWindow: 1024}, // Maintains consistency with gpt-4
+	// anthropic
+	{Name: "claude-2", Encoding: Claude, ContextWindow: 100000, MaxOutputTokens: 4096, Chat: true},
+	{Name: "claude-2.1", Encoding: Claude, ContextWindow: 200000, MaxOutputTokens: 4096, Chat: true},
+	{Name: "claude-instant-1", Encoding: Claude, ContextWindow: 100000, MaxOutputTokens: 4096, Chat: true},
+}
+
+// builtinModelPrefixes are the model families known by the prefix of their model names.
+var builtinModelPrefixes = []ModelInfo{
+	// chat
+	{Name: "o1-", Encoding: O200kBase, ContextWindow: 200000, MaxOutputTokens: 100000, Chat: true},        // e.g., o1-2024-12-17
+	{Name: "o1-preview-", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 32768, Chat: true}, // e.g., o1-preview-2024-09-12
+	{Name: "o1-mini-", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 65536, Chat: true},    // e.g., o1-mini-2024-09-12
+	{Name: "chatgpt-4o-", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 16384, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},
+	{Name: "gpt-4o-", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 16384, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},         // e.g., gpt-4o-2024-08-06
+	{Name: "gpt-4o-mini-", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 16384, Chat: true, ImageBaseTokens: 2833, ImageTileTokens: 5667}, // e.g., gpt-4o-mini-2024-07-18
+	{Name: "gpt-4-", Encoding: CL100kBase, ContextWindow: 8192, MaxOutputTokens: 8192, Chat: true},                                                       // e.g., gpt-4-0314, gpt-4-0613
+	{Name: "gpt-4-32k", Encoding: CL100kBase, ContextWindow: 32768, MaxOutputTokens: 32768, Chat: true},
+	{Name: "gpt-4-turbo-", Encoding: CL100kBase, ContextWindow: 128000, MaxOutputTokens: 4096, Chat: true},
+	{Name: "gpt-4-1106-", Encoding: CL100kBase, ContextWindow: 128000, MaxOutputTokens: 4096, Chat: true},
+	{Name: "gpt-4-0125-", Encoding: CL100kBase, ContextWindow: 128000, MaxOutputTokens: 4096, Chat: true},
+	{Name: "gpt-3.5-turbo-", Encoding: CL100kBase, ContextWindow: 16385, MaxOutputTokens: 4096, Chat: true}, // e.g, gpt-3.5-turbo-0125
+	{Name: "gpt-3.5-turbo-instruct-", Encoding: CL100kBase, ContextWindow: 4096, MaxOutputTokens: 4096},     // e.g., gpt-3.5-turbo-instruct-0914
+	{Name: "gpt-3.5-turbo-16k", Encoding: CL100kBase, ContextWindow: 16385, MaxOutputTokens: 4096, Chat: true},
+	{Name: "gpt-3.5", Encoding: CL100kBase, Chat: true},      // Common shorthand
+	{Name: "gpt-35-turbo", Encoding: CL100kBase, Chat: true}, // Azure deployment name
+	// fine-tuned
+	{Name: "ft:gpt-4o", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 16384, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},
+	{Name: "ft:gpt-4o-mini", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 16384, Chat: true, ImageBaseTokens: 2833, ImageTileTokens: 5667},
+	{Name: "ft:gpt-4", Encoding: CL100kBase, ContextWindow: 8192, MaxOutputTokens: 8192, Chat: true},
+	{Name: "ft:gpt-3.5-turbo", Encoding: CL100kBase, ContextWindow: 16385, MaxOutputTokens: 4096, Chat: true},
+	{Name: "ft:davinci-002", Encoding: CL100kBase, ContextWindow: 16384},
+	{Name: "ft:babbage-002", Encoding: CL100kBase, ContextWindow: 16384},
+	// anthropic
+	{Name: "claude-2.", Encoding: Claude, ContextWindow: 100000, MaxOutputTokens: 4096, Chat: true},         // e.g., claude-2.0
+	{Name: "claude-instant-1.", Encoding: Claude, ContextWindow: 100000, MaxOutputTokens: 4096, Chat: true}, // e.g., claude-instant-1.2
+	{Name: "claude-v1", Encoding: Claude, ContextWindow: 100000, MaxOutputTokens: 4096, Chat: true},         // e.g., claude-v1.3
+	{Name: "claude-instant-v1", Encoding: Claude, ContextWindow: 100000, MaxOutputTokens: 4096, Chat: true},
+}
+
+var (
+	modelsMu      sync.RWMutex
+	models        = modelMap(builtinModels)
+	modelPrefixes = modelMap(builtinModelPrefixes)
+
+	// builtinModelNames and builtinPrefixNames index the built-in entries, which the deprecated
+	// ModelToEncoding and ModelPrefixToEncoding maps hold for NewEncodingForModel.
+	builtinModelNames  = modelMap(builtinModels)
+	builtinPrefixNames = modelMap(builtinModelPrefixes)
+)
+
+// modelMap indexes the given models by name.
+func modelMap(infos []ModelInfo) map[string]ModelInfo {
+	m := make(map[string]ModelInfo, len(infos))
+	for _, info := range infos {
+		m[info.Name] = info
+	}
+
+	return m
+}
+
+// RegisterModel adds a model to the registry, to be found by its exact name.
+// Registering a name twice returns an error.
+func RegisterModel(info ModelInfo) error {
+	return registerModel(models, info)
+}
+
+// RegisterModelPrefix adds a model family to the registry, to be found by the prefix info.Name
+// of its model names. Registering a prefix twice returns an error.
+func RegisterModelPrefix(info ModelInfo) error {
+	return registerModel(modelPrefixes, info)
+}
+
+func registerModel(m map[string]ModelInfo, info ModelInfo) error {
+	if info.Name == "" {
+		return errors.New("model name must not be empty")
+	}
+
+	if info.Encoding == "" {
+		return fmt.Errorf("no encoding for model %s", info.Name)
+	}
+
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+
+	if _, ok := m[info.Name]; ok {
+		return fmt.Errorf("model %s already registered", info.Name)
+	}
+
+	m[info.Name] = info
+
+	return nil
+}
+
+// LookupModel returns the information about the model with the given name. Models registered by
+// their exact name take precedence over model families; of several matching families the one with
+// the longest prefix is used. The Name of the returned ModelInfo is the given name.
+func LookupModel(name string) (ModelInfo, bool) {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
+	if info, ok := models[name]; ok {
+		return info, true
+	}
+
+	var (
+		info  ModelInfo
+		match string
+		found bool
+	)
+
+	for prefix, i := range modelPrefixes {
+		if strings.HasPrefix(name, prefix) && len(prefix) > len(match) {
+			info, match, found = i, prefix, true
+		}
+	}
+
+	info.Name = name
+
+	return info, found
+}
+
+// modelEncoding returns the encoding of the model with the given name like LookupModel, but takes
+// the built-in models and model families from ModelToEncoding and ModelPrefixToEncoding, so that
+// changes to these maps take effect.
+func modelEncoding(name string) (string, bool) {
+	if encoding, ok := ModelToEncoding[name]; ok {
+		return encoding, true
+	}
+
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
+	if _, ok := builtinModelNames[name]; !ok {
+		if info, ok := models[name]; ok {
+			return info.Encoding, true
+		}
+	}
+
+	var (
+		encoding string
+		match    string
+		found    bool
+	)
+
+	for prefix, e := range ModelPrefixToEncoding {
+		if strings.HasPrefix(name, prefix) && (!found || len(prefix) > len(match)) {
+			encoding, match, found = e, prefix, true
+		}
+	}
+
+	for prefix, info := range modelPrefixes {
+		if _, ok := builtinPrefixNames[prefix]; ok {
+			continue
+		}
+
+		if strings.HasPrefix(name, prefix) && (!found || len(prefix) > len(match)) {
+			encoding, match, found = info.Encoding, prefix, true
+		}
+	}
+
+	return encoding, found
+}
+
+// Model returns the information about the model with the given name, like LookupModel.
+// It returns a ModelInfo with only the Name set if the model is unknown.
+func Model(name string) ModelInfo {
+	info, _ := LookupModel(name)
+	return info
+}
+
+// ListModels returns the sorted names of all models registered by their exact name.
+func ListModels() []string {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
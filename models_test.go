@@ -0,0 +1,175 @@
+//go:build !tiktoken_minimal
+
+package tiktoken
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    string
+		expected ModelInfo
+		found    bool
+	}{
+		{
+			name:     "exact",
+			model:    "gpt-4o",
+			expected: ModelInfo{Name: "gpt-4o", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 16384, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},
+			found:    true,
+		},
+		{
+			name:     "exact before prefix",
+			model:    "gpt-4o-2024-05-13",
+			expected: ModelInfo{Name: "gpt-4o-2024-05-13", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 4096, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},
+			found:    true,
+		},
+		{
+			name:     "prefix",
+			model:    "gpt-4o-2024-08-06",
+			expected: ModelInfo{Name: "gpt-4o-2024-08-06", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 16384, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},
+			found:    true,
+		},
+		{
+			name:     "longest prefix",
+			model:    "gpt-4-32k-0613",
+			expected: ModelInfo{Name: "gpt-4-32k-0613", Encoding: CL100kBase, ContextWindow: 32768, MaxOutputTokens: 32768, Chat: true},
+			found:    true,
+		},
+		{
+			name:     "dated o1",
+			model:    "o1-2024-12-17",
+			expected: ModelInfo{Name: "o1-2024-12-17", Encoding: O200kBase, ContextWindow: 200000, MaxOutputTokens: 100000, Chat: true},
+			found:    true,
+		},
+		{
+			name:     "dated o1 preview",
+			model:    "o1-preview-2024-09-12",
+			expected: ModelInfo{Name: "o1-preview-2024-09-12", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 32768, Chat: true},
+			found:    true,
+		},
+		{
+			name:     "dated o1 mini",
+			model:    "o1-mini-2024-09-12",
+			expected: ModelInfo{Name: "o1-mini-2024-09-12", Encoding: O200kBase, ContextWindow: 128000, MaxOutputTokens: 65536, Chat: true},
+			found:    true,
+		},
+		{
+			name:     "instruct model",
+			model:    "gpt-3.5-turbo-instruct-0914",
+			expected: ModelInfo{Name: "gpt-3.5-turbo-instruct-0914", Encoding: CL100kBase, ContextWindow: 4096, MaxOutputTokens: 4096},
+			found:    true,
+		},
+		{
+			name:     "vision preview",
+			model:    "gpt-4-vision-preview",
+			expected: ModelInfo{Name: "gpt-4-vision-preview", Encoding: CL100kBase, ContextWindow: 128000, MaxOutputTokens: 4096, Chat: true, ImageBaseTokens: 85, ImageTileTokens: 170},
+			found:    true,
+		},
+		{
+			name:     "turbo preview",
+			model:    "gpt-4-turbo-preview",
+			expected: ModelInfo{Name: "gpt-4-turbo-preview", Encoding: CL100kBase, ContextWindow: 128000, MaxOutputTokens: 4096, Chat: true},
+			found:    true,
+		},
+		{
+			name:     "completion model",
+			model:    "text-davinci-003",
+			expected: ModelInfo{Name: "text-davinci-003", Encoding: P50kBase, ContextWindow: 4097},
+			found:    true,
+		},
+		{
+			name:     "unknown",
+			model:    "UnknownModel",
+			expected: ModelInfo{Name: "UnknownModel"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			info, found := LookupModel(tt.model)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.expected, info)
+			assert.Equal(t, tt.expected, Model(tt.model))
+		})
+	}
+}
+
+func TestModelsHaveRegisteredEncodings(t *testing.T) {
+	encodings := map[string]bool{}
+	for _, name := range ListEncodings() {
+		encodings[name] = true
+	}
+
+	for _, info := range append(append([]ModelInfo{}, builtinModels...), builtinModelPrefixes...) {
+		assert.True(t, encodings[info.Encoding], "model %s", info.Name)
+		assert.True(t, info.MaxOutputTokens <= info.ContextWindow || info.ContextWindow == 0, "model %s", info.Name)
+	}
+}
+
+func TestRegisterModel(t *testing.T) {
+	t.Cleanup(func() {
+		modelsMu.Lock()
+		defer modelsMu.Unlock()
+
+		delete(models, "my-model")
+		delete(modelPrefixes, "my-model-")
+	})
+
+	require.NoError(t, RegisterModel(ModelInfo{Name: "my-model", Encoding: CL100kBase, ContextWindow: 1000}))
+	require.NoError(t, RegisterModelPrefix(ModelInfo{Name: "my-model-", Encoding: O200kBase, ContextWindow: 2000}))
+
+	assert.Equal(t, 1000, Model("my-model").ContextWindow)
+	assert.Equal(t, 2000, Model("my-model-v2").ContextWindow)
+	assert.Contains(t, ListModels(), "my-model")
+
+	encoding, err := NewEncodingForModel("my-model-v2")
+	require.NoError(t, err)
+	assert.Equal(t, O200kBase, encoding.Name())
+
+	assert.Error(t, RegisterModel(ModelInfo{Name: "my-model", Encoding: CL100kBase}))
+	assert.Error(t, RegisterModel(ModelInfo{Name: "", Encoding: CL100kBase}))
+	assert.Error(t, RegisterModelPrefix(ModelInfo{Name: "other-"}))
+}
+
+func TestLegacyModelMaps(t *testing.T) {
+	t.Cleanup(func() {
+		ModelToEncoding["gpt-4"] = CL100kBase
+		ModelPrefixToEncoding["gpt-4o-"] = O200kBase
+
+		delete(ModelToEncoding, "my-legacy-model")
+		delete(ModelPrefixToEncoding, "gpt-4o-2024-")
+	})
+
+	ModelToEncoding["gpt-4"] = O200kBase
+	ModelToEncoding["my-legacy-model"] = P50kBase
+	ModelPrefixToEncoding["gpt-4o-"] = CL100kBase
+	ModelPrefixToEncoding["gpt-4o-2024-"] = R50kBase
+
+	tests := []struct {
+		model    string
+		expected string
+	}{
+		{model: "gpt-4", expected: O200kBase},
+		{model: "my-legacy-model", expected: P50kBase},
+		{model: "gpt-4o-2025-01-01", expected: CL100kBase},
+		{model: "gpt-4o-2024-08-06", expected: R50kBase},
+		{model: "gpt-4o-mini-2024-07-18", expected: O200kBase},
+		{model: "gpt-4o", expected: O200kBase},
+	}
+
+	for _, tt := range tests {
+		encoding, err := NewEncodingForModel(tt.model)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, encoding.Name(), "model %s", tt.model)
+	}
+
+	// the registry is not affected
+	assert.Equal(t, CL100kBase, Model("gpt-4").Encoding)
+}
@@ -0,0 +1,105 @@
+package tiktoken
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// newNormalizer returns the unicode normalization form with the given name.
+// It returns nil if no normalization is requested.
+func newNormalizer(form string) (*norm.Form, error) {
+	var f norm.Form
+
+	switch form {
+	case "":
+		return nil, nil
+	case NFC:
+		f = norm.NFC
+	case NFD:
+		f = norm.NFD
+	case NFKC:
+		f = norm.NFKC
+	case NFKD:
+		f = norm.NFKD
+	default:
+		return nil, fmt.Errorf("unsupported normalization: %s", form)
+	}
+
+	return &f, nil
+}
+
+// normalizeText applies the Encoding's unicode normalization, if any, to the given text.
+func (enc *Encoding) normalizeText(text string) string {
+	if enc.normalization == nil {
+		return text
+	}
+
+	return enc.normalization.String(text)
+}
+
+// normalizedSegment maps a segment of normalized text to the segment of the original text it was produced from.
+type normalizedSegment struct {
+	start, end       int // byte range in the normalized text
+	srcStart, srcEnd int // byte range in the original text
+}
+
+// offsetMapping translates byte offsets in normalized text back to the original text.
+// A nil mapping is the identity.
+type offsetMapping []normalizedSegment
+
+// normalizeTextWithOffsets applies the Encoding's unicode normalization, if any, to the given text
+// and returns a mapping from offsets in the normalized text to offsets in text.
+func (enc *Encoding) normalizeTextWithOffsets(text string) (string, offsetMapping) {
+	if enc.normalization == nil {
+		return text, nil
+	}
+
+	var (
+		iter     norm.Iter
+		out      []byte
+		segments offsetMapping
+	)
+
+	iter.InitString(*enc.normalization, text)
+
+	for !iter.Done() {
+		srcStart := iter.Pos()
+		seg := iter.Next()
+
+		segments = append(segments, normalizedSegment{
+			start:    len(out),
+			end:      len(out) + len(seg),
+			srcStart: srcStart,
+			srcEnd:   iter.Pos(),
+		})
+
+		out = append(out, seg...)
+	}
+
+	return string(out), segments
+}
+
+// span translates the byte range [start, end) of the normalized text to the original text.
+// Ranges starting or ending inside a normalized segment are widened to the whole segment.
+func (m offsetMapping) span(start, end int) TokenOffset {
+	if m == nil {
+		return TokenOffset{Start: start, End: end}
+	}
+
+	first := sort.Search(len(m), func(i int) bool { return m[i].end > start })
+	last := sort.Search(len(m), func(i int) bool { return m[i].end >= end })
+
+	if first == len(m) || last == len(m) {
+		// only possible for empty ranges at the end of the text
+		n := 0
+		if len(m) > 0 {
+			n = m[len(m)-1].srcEnd
+		}
+
+		return TokenOffset{Start: n, End: n}
+	}
+
+	return TokenOffset{Start: m[first].srcStart, End: m[last].srcEnd}
+}
@@ -0,0 +1,59 @@
+package tiktoken
+
+// EncodeOption configures how Encode and related methods treat special tokens in the text.
+//
+// By default, like in Python tiktoken, no special token is allowed and text containing any
+// special token is rejected with an error.
+type EncodeOption func(*encodeOptions)
+
+// encodeOptions holds the resolved EncodeOptions.
+type encodeOptions struct {
+	allowedSpecial    []string
+	allowAllSpecial   bool
+	disallowedSpecial []string
+	disallowAll       bool
+}
+
+// newEncodeOptions applies the given options to the defaults.
+func newEncodeOptions(optFns []EncodeOption) encodeOptions {
+	opts := encodeOptions{disallowAll: true}
+
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	return opts
+}
+
+// WithAllowedSpecial allows the given special tokens to be encoded as special tokens.
+func WithAllowedSpecial(tokens ...string) EncodeOption {
+	return func(o *encodeOptions) {
+		o.allowedSpecial = append(o.allowedSpecial, tokens...)
+	}
+}
+
+// WithAllSpecialAllowed allows all special tokens of the Encoding to be encoded as special tokens.
+func WithAllSpecialAllowed() EncodeOption {
+	return func(o *encodeOptions) {
+		o.allowAllSpecial = true
+	}
+}
+
+// WithDisallowedSpecial rejects text containing one of the given special tokens with an error.
+// Special tokens that are neither allowed nor disallowed are encoded as ordinary text.
+// It replaces the default of disallowing all special tokens that are not allowed.
+func WithDisallowedSpecial(tokens ...string) EncodeOption {
+	return func(o *encodeOptions) {
+		o.disallowAll = false
+		o.disallowedSpecial = append(o.disallowedSpecial, tokens...)
+	}
+}
+
+// WithSpecialAsText encodes all special tokens that are not allowed as ordinary text instead of
+// rejecting the text. It is equivalent to disallowed_special=() in Python tiktoken.
+func WithSpecialAsText() EncodeOption {
+	return func(o *encodeOptions) {
+		o.disallowAll = false
+		o.disallowedSpecial = nil
+	}
+}
@@ -1,18 +1,16 @@
-package tiktoken
+//go:build !tiktoken_minimal || tiktoken_p50k_base
 
-import (
-	_ "embed"
-	"strings"
-)
+package tiktoken
 
-//go:embed resource/p50k_base.tiktoken
-var p50kBase string
+func init() {
+	MustRegisterEncoding(P50kBase, NewP50kBase)
+}
 
 // NewP50kBase creates a new Codec instance for the P50k_base tokenization scheme.
 // It loads the mergeable ranks from the embedded p50kBase resource.
 // The function returns a pointer to the Codec or an error if any.
 func NewP50kBase() (*Codec, error) {
-	ranks, err := ConvertToMergeableBPERanks(strings.NewReader(p50kBase))
+	ranks, err := parseBinaryRanks(p50kBase)
 	if err != nil {
 		return nil, err
 	}
@@ -20,7 +18,7 @@ func NewP50kBase() (*Codec, error) {
 	return &Codec{
 		Name:           "p50k_base",
 		ExplicitNVocab: 50281,
-		PatStr:         `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`,
+		PatStr:         r50kPatStr,
 		MergeableRanks: ranks,
 		SpecialTokens: map[string]uint{
 			EndOfText: 50256,
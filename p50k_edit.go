@@ -1,22 +1,23 @@
+//go:build !tiktoken_minimal || tiktoken_p50k_edit
+
 package tiktoken
 
-import (
-	_ "embed"
-	"strings"
-)
+func init() {
+	MustRegisterEncoding(P50kEdit, NewP50kEdit)
+}
 
 // NewP50kEdit creates a new Codec instance for the P50k_edit tokenization scheme.
 // It loads the mergeable ranks from the embedded p50kBase resource.
 // The function returns a pointer to the Codec or an error if any.
 func NewP50kEdit() (*Codec, error) {
-	ranks, err := ConvertToMergeableBPERanks(strings.NewReader(p50kBase))
+	ranks, err := parseBinaryRanks(p50kBase)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Codec{
 		Name:           "p50k_edit",
-		PatStr:         `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`,
+		PatStr:         r50kPatStr,
 		MergeableRanks: ranks,
 		SpecialTokens: map[string]uint{
 			EndOfText: 50256,
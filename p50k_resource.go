@@ -0,0 +1,12 @@
+//go:build !tiktoken_minimal || tiktoken_p50k_base || tiktoken_p50k_edit
+
+package tiktoken
+
+import _ "embed"
+
+//go:generate go run ./cmd/tiktoken-compile resource/p50k_base.tiktoken
+
+// p50kBase holds the ranks shared by the p50k_base and p50k_edit encodings.
+//
+//go:embed resource/p50k_base.tkrb
+var p50kBase string
@@ -0,0 +1,343 @@
+package tiktoken
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dlclark/regexp2"
+)
+
+// Pre-tokenization patterns of the built-in encodings.
+const (
+	r50kPatStr   = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
+	cl100kPatStr = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+	o200kPatStr  = `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+)
+
+// preTokenizer splits text into the pieces that are encoded independently by byte pair encoding.
+type preTokenizer interface {
+	// split calls fn with the byte range of every piece of text, in order.
+	split(text string, fn func(start, end int))
+}
+
+// newPreTokenizer returns a pre-tokenizer for the given pattern. Known patterns are matched by
+// hand-written splitters that produce the same pieces as the regular expression without
+// backtracking or allocations; all other patterns are compiled with regexp2.
+func newPreTokenizer(pattern string) (preTokenizer, error) {
+	switch pattern {
+	case r50kPatStr:
+		return pieceFunc(nextR50kPiece), nil
+	case cl100kPatStr:
+		return pieceFunc(nextCL100kPiece), nil
+	case o200kPatStr:
+		return pieceFunc(nextO200kPiece), nil
+	}
+
+	regex, err := regexp2.Compile(pattern, regexp2.None)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling regex: %s", err)
+	}
+
+	return &regexPreTokenizer{regex: regex}, nil
+}
+
+// regexPreTokenizer splits text at the matches of a regular expression.
+type regexPreTokenizer struct {
+	regex *regexp2.Regexp
+}
+
+func (p *regexPreTokenizer) split(text string, fn func(start, end int)) {
+	forEachRegex2Match(text, p.regex, fn)
+}
+
+// pieceFunc returns the end of the piece starting at byte offset start of text.
+// The patterns it implements match at every position, so pieces are contiguous.
+type pieceFunc func(text string, start int) int
+
+func (next pieceFunc) split(text string, fn func(start, end int)) {
+	for start := 0; start < len(text); {
+		end := next(text, start)
+		fn(start, end)
+		start = end
+	}
+}
+
+// nextR50kPiece implements r50kPatStr:
+//
+//	's|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+
+func nextR50kPiece(text string, i int) int {
+	r0, _ := decodeRune(text, i)
+
+	if r0 == '\'' {
+		if end := matchContraction(text, i, false); end >= 0 {
+			return end
+		}
+	}
+
+	start := i
+	if r0 == ' ' {
+		start++
+	}
+
+	switch r, _ := decodeRune(text, start); {
+	case isLetter(r):
+		return skipRunes(text, start, isLetter)
+	case isNumber(r):
+		return skipRunes(text, start, isNumber)
+	case isPunct(r):
+		return skipRunes(text, start, isPunct)
+	}
+
+	return matchSpaces(text, i)
+}
+
+// nextCL100kPiece implements cl100kPatStr:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func nextCL100kPiece(text string, i int) int {
+	r0, n0 := decodeRune(text, i)
+
+	if r0 == '\'' {
+		if end := matchContraction(text, i, true); end >= 0 {
+			return end
+		}
+	}
+
+	if isPrefix(r0) {
+		if r1, _ := decodeRune(text, i+n0); isLetter(r1) {
+			return skipRunes(text, i+n0, isLetter)
+		}
+	}
+
+	if isLetter(r0) {
+		return skipRunes(text, i, isLetter)
+	}
+
+	if isNumber(r0) {
+		return skipRunesN(text, i, isNumber, 3)
+	}
+
+	return matchPunctOrSpaces(text, i, "\r\n")
+}
+
+// nextO200kPiece implements o200kPatStr:
+//
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func nextO200kPiece(text string, i int) int {
+	r0, n0 := decodeRune(text, i)
+
+	// Both word alternatives are tried with the optional prefix first, then without it.
+	if isPrefix(r0) {
+		if end := matchLowerWord(text, i+n0); end >= 0 {
+			return end
+		}
+	}
+
+	if end := matchLowerWord(text, i); end >= 0 {
+		return end
+	}
+
+	if isPrefix(r0) {
+		if end := matchUpperWord(text, i+n0); end >= 0 {
+			return end
+		}
+	}
+
+	if end := matchUpperWord(text, i); end >= 0 {
+		return end
+	}
+
+	if isNumber(r0) {
+		return skipRunesN(text, i, isNumber, 3)
+	}
+
+	return matchPunctOrSpaces(text, i, "\r\n/")
+}
+
+// matchLowerWord matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+ followed by an optional
+// contraction at byte offset i. It returns the end of the match or -1.
+func matchLowerWord(text string, i int) int {
+	upperEnd := skipRunes(text, i, isUpperish)
+
+	if r, _ := decodeRune(text, upperEnd); isLowerish(r) {
+		return matchOptionalContraction(text, skipRunes(text, upperEnd, isLowerish))
+	}
+
+	// Backtrack into the upper case run: the lower case part has to start at the last rune
+	// belonging to both classes. It ends right after it, as all following runes are upper case only.
+	for k := upperEnd; k > i; {
+		r, size := utf8.DecodeLastRuneInString(text[i:k])
+		if isLowerish(r) {
+			return matchOptionalContraction(text, k)
+		}
+
+		k -= size
+	}
+
+	return -1
+}
+
+// matchUpperWord matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]* followed by an optional
+// contraction at byte offset i. It returns the end of the match or -1.
+func matchUpperWord(text string, i int) int {
+	upperEnd := skipRunes(text, i, isUpperish)
+	if upperEnd == i {
+		return -1
+	}
+
+	return matchOptionalContraction(text, skipRunes(text, upperEnd, isLowerish))
+}
+
+// matchOptionalContraction matches (?i:'s|'t|'re|'ve|'m|'ll|'d)? at byte offset i and returns the end of the match.
+func matchOptionalContraction(text string, i int) int {
+	if r, _ := decodeRune(text, i); r == '\'' {
+		if end := matchContraction(text, i, true); end >= 0 {
+			return end
+		}
+	}
+
+	return i
+}
+
+var contractions = []string{"s", "t", "re", "ve", "m", "ll", "d"}
+
+// matchContraction matches 's|'t|'re|'ve|'m|'ll|'d at the apostrophe at byte offset i.
+// It returns the end of the match or -1.
+func matchContraction(text string, i int, ignoreCase bool) int {
+	for _, suffix := range contractions {
+		end := i + 1
+		ok := true
+
+		for _, c := range suffix {
+			r, size := decodeRune(text, end)
+			if ignoreCase {
+				// regexp2 compares the lower case of the input with the lower case pattern
+				r = unicode.ToLower(r)
+			}
+
+			if r != c {
+				ok = false
+				break
+			}
+
+			end += size
+		}
+
+		if ok {
+			return end
+		}
+	}
+
+	return -1
+}
+
+// matchPunctOrSpaces matches the trailing alternatives shared by cl100k_base and o200k_base at byte offset i:
+//
+//	?[^\s\p{L}\p{N}]+[<trailing>]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func matchPunctOrSpaces(text string, i int, trailing string) int {
+	start := i
+	if r0, _ := decodeRune(text, i); r0 == ' ' {
+		start++
+	}
+
+	if r, _ := decodeRune(text, start); isPunct(r) {
+		end := skipRunes(text, start, isPunct)
+
+		for end < len(text) && strings.IndexByte(trailing, text[end]) >= 0 {
+			end++
+		}
+
+		return end
+	}
+
+	// \s*[\r\n]+ backtracks to the last line break of the whitespace run
+	spaceEnd := skipRunes(text, i, unicode.IsSpace)
+	if k := strings.LastIndexAny(text[i:spaceEnd], "\r\n"); k >= 0 {
+		return i + k + 1
+	}
+
+	return matchSpaces(text, i)
+}
+
+// matchSpaces matches \s+(?!\S)|\s+ at byte offset i.
+func matchSpaces(text string, i int) int {
+	end := skipRunes(text, i, unicode.IsSpace)
+	if end == len(text) {
+		return end
+	}
+
+	// The run is followed by a non-whitespace rune, so \s+(?!\S) leaves the last whitespace
+	// rune to the next piece, unless that would leave the match empty.
+	if _, size := utf8.DecodeLastRuneInString(text[i:end]); end-size > i {
+		return end - size
+	}
+
+	return end
+}
+
+// decodeRune returns the rune at byte offset i of text and its width in bytes.
+// Invalid UTF-8 decodes to utf8.RuneError with width 1, like in regexp2. At the end of text it returns (-1, 0).
+func decodeRune(text string, i int) (rune, int) {
+	if i >= len(text) {
+		return -1, 0
+	}
+
+	if c := text[i]; c < utf8.RuneSelf {
+		return rune(c), 1
+	}
+
+	return utf8.DecodeRuneInString(text[i:])
+}
+
+// skipRunes returns the byte offset of the first rune at or after byte offset i that does not satisfy pred.
+func skipRunes(text string, i int, pred func(rune) bool) int {
+	return skipRunesN(text, i, pred, -1)
+}
+
+// skipRunesN is like skipRunes but skips at most n runes. A negative n means no limit.
+func skipRunesN(text string, i int, pred func(rune) bool, n int) int {
+	for ; n != 0 && i < len(text); n-- {
+		r, size := decodeRune(text, i)
+		if !pred(r) {
+			break
+		}
+
+		i += size
+	}
+
+	return i
+}
+
+// isLetter reports whether r matches \p{L}.
+func isLetter(r rune) bool {
+	return unicode.IsLetter(r)
+}
+
+// isNumber reports whether r matches \p{N}.
+func isNumber(r rune) bool {
+	return unicode.IsNumber(r)
+}
+
+// isPunct reports whether r matches [^\s\p{L}\p{N}].
+func isPunct(r rune) bool {
+	return r >= 0 && !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// isPrefix reports whether r matches [^\r\n\p{L}\p{N}].
+func isPrefix(r rune) bool {
+	return r >= 0 && r != '\r' && r != '\n' && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// isUpperish reports whether r matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}].
+func isUpperish(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// isLowerish reports whether r matches [\p{Ll}\p{Lm}\p{Lo}\p{M}].
+func isLowerish(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
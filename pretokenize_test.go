@@ -0,0 +1,174 @@
+//go:build !tiktoken_minimal
+
+package tiktoken
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/dlclark/regexp2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var knownPatterns = map[string]string{
+	"r50k":   r50kPatStr,
+	"cl100k": cl100kPatStr,
+	"o200k":  o200kPatStr,
+}
+
+// splitPieces returns the pieces p splits text into.
+func splitPieces(p preTokenizer, text string) []string {
+	var pieces []string
+
+	p.split(text, func(start, end int) {
+		pieces = append(pieces, text[start:end])
+	})
+
+	return pieces
+}
+
+// differentialCorpus returns texts exercising every character class of the known patterns,
+// including the token strings of the built-in vocabularies.
+func differentialCorpus(t *testing.T) []string {
+	t.Helper()
+
+	fragments := []string{
+		"hello", "Hello", "HELLO", "hELLO", " world", "  ", "   ", "\t", "\n", "\r\n", "\n\n", " \n ", "\v", "\f",
+		"'s", "'S", "'t", "'re", "'RE", "'Ve", "'m", "'ll", "'LL", "'d", "'", "''", "'x", "don't", "I'M",
+		"1", "12", "1234567", "٣٤٥", "７", "²", "½", "Ⅻ",
+		".", ",", "!?", "...", "/", "//", "(", ")", "-", "_", "\"", "#", "$", "€", "😀", "👩‍💻", "™",
+		"é", "ß", "Ǆ", "ǅ", "ǆ", "ʰ", "ˈ", "你好", "世界", "ا", "こんにちは", "Привет", "ΑΒΓ",
+		"́", "̈", "é", "É", "ि", "कि", "⃝", " ", "\u0085", " ", "　",
+		"\xff", "\xe4\xbd", "\x00", "<|endoftext|>",
+	}
+
+	rng := rand.New(rand.NewSource(1))
+
+	var corpus []string
+
+	for i := 0; i < 2000; i++ {
+		var sb strings.Builder
+
+		for j := rng.Intn(40); j > 0; j-- {
+			if rng.Intn(8) == 0 {
+				sb.WriteRune(rune(rng.Intn(0x10ffff)))
+				continue
+			}
+
+			sb.WriteString(fragments[rng.Intn(len(fragments))])
+		}
+
+		corpus = append(corpus, sb.String())
+	}
+
+	for _, name := range []string{CL100kBase, O200kBase} {
+		enc, err := NewEncodingByName(name)
+		require.NoError(t, err)
+
+		tokens := make([]string, 0, len(enc.coreBPE.encoder))
+		for token := range enc.coreBPE.encoder {
+			tokens = append(tokens, token)
+		}
+
+		sort.Slice(tokens, func(i, j int) bool {
+			return enc.coreBPE.encoder[tokens[i]] < enc.coreBPE.encoder[tokens[j]]
+		})
+
+		for i := 0; i < len(tokens); i += 500 {
+			end := i + 500
+			if end > len(tokens) {
+				end = len(tokens)
+			}
+
+			corpus = append(corpus, strings.Join(tokens[i:end], ""))
+		}
+	}
+
+	return corpus
+}
+
+func TestPreTokenizerMatchesRegex(t *testing.T) {
+	corpus := differentialCorpus(t)
+
+	for name, pattern := range knownPatterns {
+		pattern := pattern
+
+		t.Run(name, func(t *testing.T) {
+			splitter, err := newPreTokenizer(pattern)
+			require.NoError(t, err)
+			require.IsType(t, pieceFunc(nil), splitter)
+
+			regex := &regexPreTokenizer{regex: regexp2.MustCompile(pattern, regexp2.None)}
+
+			for _, text := range corpus {
+				if !assert.Equal(t, splitPieces(regex, text), splitPieces(splitter, text), "text %q", text) {
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestNewPreTokenizer(t *testing.T) {
+	t.Run("custom pattern", func(t *testing.T) {
+		p, err := newPreTokenizer(`\S+|\s+`)
+		require.NoError(t, err)
+		assert.IsType(t, &regexPreTokenizer{}, p)
+		assert.Equal(t, []string{"héllo", " ", "wörld"}, splitPieces(p, "héllo wörld"))
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := newPreTokenizer(`(`)
+		assert.Error(t, err)
+	})
+}
+
+func FuzzPreTokenizer(f *testing.F) {
+	f.Add("hello world")
+	f.Add("I'M here   \n\n  now's 12345 ...!! 你好")
+	f.Add("ΑΒΓabcǅʰ́x 　 \r\n/")
+	f.Add("\xff\xfe'LL")
+
+	splitters := map[string][2]preTokenizer{}
+
+	for name, pattern := range knownPatterns {
+		splitter, err := newPreTokenizer(pattern)
+		require.NoError(f, err)
+
+		splitters[name] = [2]preTokenizer{splitter, &regexPreTokenizer{regex: regexp2.MustCompile(pattern, regexp2.None)}}
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		for name, s := range splitters {
+			assert.Equal(t, splitPieces(s[1], text), splitPieces(s[0], text), "%s: text %q", name, text)
+		}
+	})
+}
+
+func BenchmarkPreTokenizer(b *testing.B) {
+	for name, pattern := range knownPatterns {
+		splitter, err := newPreTokenizer(pattern)
+		require.NoError(b, err)
+
+		regex := &regexPreTokenizer{regex: regexp2.MustCompile(pattern, regexp2.None)}
+
+		b.Run(name+"/handwritten", func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				splitter.split(benchmarkText, func(int, int) {})
+			}
+		})
+
+		b.Run(name+"/regexp2", func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				regex.split(benchmarkText, func(int, int) {})
+			}
+		})
+	}
+}
@@ -1,18 +1,23 @@
+//go:build !tiktoken_minimal || tiktoken_r50k_base
+
 package tiktoken
 
-import (
-	_ "embed"
-	"strings"
-)
+import _ "embed"
+
+//go:generate go run ./cmd/tiktoken-compile resource/r50k_base.tiktoken
 
-//go:embed resource/r50k_base.tiktoken
+//go:embed resource/r50k_base.tkrb
 var r50kBase string
 
+func init() {
+	MustRegisterEncoding(R50kBase, NewR50kBase)
+}
+
 // NewR50kBase creates a new Codec instance for the R50k_base tokenization scheme.
 // It loads the mergeable ranks from the embedded r50kBase resource.
 // The function returns a pointer to the Codec or an error if any.
 func NewR50kBase() (*Codec, error) {
-	ranks, err := ConvertToMergeableBPERanks(strings.NewReader(r50kBase))
+	ranks, err := parseBinaryRanks(r50kBase)
 	if err != nil {
 		return nil, err
 	}
@@ -20,7 +25,7 @@ func NewR50kBase() (*Codec, error) {
 	return &Codec{
 		Name:           "r50k_base",
 		ExplicitNVocab: 50257,
-		PatStr:         `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`,
+		PatStr:         r50kPatStr,
 		MergeableRanks: ranks,
 		SpecialTokens: map[string]uint{
 			EndOfText: 50256,
@@ -0,0 +1,167 @@
+package tiktoken
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// The binary rank format stores mergeable ranks in a form that can be loaded without parsing
+// text. All integers are little-endian.
+//
+//	magic   [4]byte  "TKRB"
+//	version uint32   1
+//	count   uint32   number of tokens
+//	index   count × (rank uint32, offset uint32), sorted by rank
+//	data    count × (uvarint length, token bytes), in rank order
+//
+// Offsets are relative to the start of the data section.
+const (
+	binaryRanksMagic   = "TKRB"
+	binaryRanksVersion = 1
+
+	binaryRanksHeaderSize    = 12
+	binaryRanksIndexItemSize = 8
+)
+
+// WriteBinaryRanks writes the mergeable ranks to w in the binary rank format.
+func WriteBinaryRanks(w io.Writer, ranks map[string]uint) error {
+	tokens := rankedTokens(ranks)
+
+	index := make([]byte, 0, len(tokens)*binaryRanksIndexItemSize)
+	data := make([]byte, 0, len(tokens)*8)
+
+	for i, token := range tokens {
+		rank := ranks[token]
+		if rank > math.MaxUint32 {
+			return fmt.Errorf("rank out of uint32 range: %d", rank)
+		}
+
+		if i > 0 && rank == ranks[tokens[i-1]] {
+			return fmt.Errorf("duplicate rank: %d", rank)
+		}
+
+		if uint64(len(data)) > math.MaxUint32 {
+			return errors.New("ranks too large for binary rank format")
+		}
+
+		index = binary.LittleEndian.AppendUint32(index, uint32(rank))
+		index = binary.LittleEndian.AppendUint32(index, uint32(len(data)))
+
+		data = binary.AppendUvarint(data, uint64(len(token)))
+		data = append(data, token...)
+	}
+
+	header := make([]byte, 0, binaryRanksHeaderSize)
+	header = append(header, binaryRanksMagic...)
+	header = binary.LittleEndian.AppendUint32(header, binaryRanksVersion)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(tokens)))
+
+	for _, b := range [][]byte{header, index, data} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadBinaryRanks reads mergeable ranks in the binary rank format from r.
+func ReadBinaryRanks(r io.Reader) (map[string]uint, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseBinaryRanks(data)
+}
+
+// ParseBinaryRanks parses mergeable ranks in the binary rank format. The returned tokens do
+// not reference data, so it may be reused afterwards.
+func ParseBinaryRanks(data []byte) (map[string]uint, error) {
+	// a single copy of data backs all tokens
+	return parseBinaryRanks(string(data))
+}
+
+// parseBinaryRanks parses mergeable ranks in the binary rank format. The returned tokens are
+// substrings of data, which lets them share the memory of embedded or mapped rank files.
+func parseBinaryRanks(data string) (map[string]uint, error) {
+	if !strings.HasPrefix(data, binaryRanksMagic) {
+		return nil, errors.New("invalid binary ranks: bad magic")
+	}
+
+	if len(data) < binaryRanksHeaderSize {
+		return nil, errors.New("invalid binary ranks: truncated header")
+	}
+
+	if version := le32(data[4:]); version != binaryRanksVersion {
+		return nil, fmt.Errorf("invalid binary ranks: unsupported version %d", version)
+	}
+
+	count := int(le32(data[8:]))
+
+	indexEnd := binaryRanksHeaderSize + count*binaryRanksIndexItemSize
+	if indexEnd < binaryRanksHeaderSize || indexEnd > len(data) {
+		return nil, errors.New("invalid binary ranks: truncated index")
+	}
+
+	index := data[binaryRanksHeaderSize:indexEnd]
+
+	section := data[indexEnd:]
+
+	ranks := make(map[string]uint, count)
+
+	for i := 0; i < count; i++ {
+		item := index[i*binaryRanksIndexItemSize:]
+		rank := le32(item)
+		offset := int(le32(item[4:]))
+
+		if i > 0 && rank <= le32(index[(i-1)*binaryRanksIndexItemSize:]) {
+			return nil, fmt.Errorf("invalid binary ranks: rank %d out of order", rank)
+		}
+
+		if offset < 0 || offset >= len(section) {
+			return nil, fmt.Errorf("invalid binary ranks: offset out of range for rank %d", rank)
+		}
+
+		length, n := uvarint(section[offset:])
+		if n <= 0 || length > uint64(len(section)-offset-n) {
+			return nil, fmt.Errorf("invalid binary ranks: bad length for rank %d", rank)
+		}
+
+		start := offset + n
+		token := section[start : start+int(length)]
+
+		if _, ok := ranks[token]; ok {
+			return nil, fmt.Errorf("invalid binary ranks: duplicate token for rank %d", rank)
+		}
+
+		ranks[token] = uint(rank)
+	}
+
+	return ranks, nil
+}
+
+// le32 decodes a little-endian uint32 from the start of s.
+func le32(s string) uint32 {
+	return uint32(s[0]) | uint32(s[1])<<8 | uint32(s[2])<<16 | uint32(s[3])<<24
+}
+
+// uvarint decodes a uvarint from the start of s like binary.Uvarint.
+func uvarint(s string) (uint64, int) {
+	if len(s) > binary.MaxVarintLen64 {
+		s = s[:binary.MaxVarintLen64]
+	}
+
+	// the conversion of at most MaxVarintLen64 bytes does not allocate
+	return binary.Uvarint([]byte(s))
+}
+
+// isBinaryRanks reports whether data starts with the magic of the binary rank format.
+func isBinaryRanks(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(binaryRanksMagic))
+}
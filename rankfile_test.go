@@ -0,0 +1,178 @@
+package tiktoken
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smallRankFile holds the tokens "a", "b", "c", "ab" and "abc".
+const smallRankFile = "YQ== 0\nYg== 1\nYw== 2\nYWI= 3\nYWJj 4\n"
+
+func TestBinaryRanksRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("resource/*.tiktoken")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		file := file
+
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+
+			expected, err := ConvertToMergeableBPERanks(bytes.NewReader(data))
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, WriteBinaryRanks(&buf, expected))
+			assert.Less(t, buf.Len(), len(data))
+
+			// the embedded vocabularies are generated with go generate
+			embedded, err := os.ReadFile(strings.TrimSuffix(file, ".tiktoken") + ".tkrb")
+			require.NoError(t, err)
+			assert.Equal(t, embedded, buf.Bytes())
+
+			ranks, err := ReadBinaryRanks(&buf)
+			require.NoError(t, err)
+			assert.Equal(t, expected, ranks)
+		})
+	}
+}
+
+func TestLoadCodecFromBinaryFile(t *testing.T) {
+	ranks, err := ConvertToMergeableBPERanks(strings.NewReader(smallRankFile))
+	require.NoError(t, err)
+
+	filename := filepath.Join(t.TempDir(), "small.tkrb")
+
+	f, err := os.Create(filename)
+	require.NoError(t, err)
+	require.NoError(t, WriteBinaryRanks(f, ranks))
+	require.NoError(t, f.Close())
+
+	codec, err := LoadCodecFromFile(filename, `\S+|\s+`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "small", codec.Name)
+	assert.Equal(t, ranks, codec.MergeableRanks)
+
+	t.Run("embedded vocabulary", func(t *testing.T) {
+		data, err := os.ReadFile("resource/cl100k_base.tkrb")
+		require.NoError(t, err)
+
+		filename := filepath.Join(t.TempDir(), "cl100k_base.tkrb")
+		require.NoError(t, os.WriteFile(filename, data, 0o600))
+
+		codec, err := LoadCodecFromFile(filename, cl100kPatStr, map[string]uint{EndOfText: 100257})
+		require.NoError(t, err)
+
+		encoding, err := NewEncoding(codec)
+		require.NoError(t, err)
+
+		// the tokens reference the mapping, which outlives the file
+		require.NoError(t, os.Remove(filename))
+		runtime.GC()
+
+		ids, _, err := encoding.Encode("hello world<|endoftext|>", WithAllSpecialAllowed())
+		require.NoError(t, err)
+		assert.Equal(t, []uint{15339, 1917, 100257}, ids)
+		assert.Equal(t, "hello world<|endoftext|>", string(encoding.Decode(ids)))
+	})
+}
+
+func TestParseBinaryRanksErrors(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteBinaryRanks(&buf, map[string]uint{"a": 0, "b": 1, "ab": 2}))
+
+	valid := buf.Bytes()
+
+	corrupt := func(fn func(data []byte) []byte) []byte {
+		return fn(append([]byte{}, valid...))
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{
+			name:     "bad magic",
+			data:     []byte("YQ== 0\n"),
+			expected: "invalid binary ranks: bad magic",
+		},
+		{
+			name:     "truncated header",
+			data:     valid[:8],
+			expected: "invalid binary ranks: truncated header",
+		},
+		{
+			name:     "unsupported version",
+			data:     corrupt(func(data []byte) []byte { data[4] = 2; return data }),
+			expected: "invalid binary ranks: unsupported version 2",
+		},
+		{
+			name:     "truncated index",
+			data:     valid[:20],
+			expected: "invalid binary ranks: truncated index",
+		},
+		{
+			name:     "truncated data",
+			data:     valid[:len(valid)-1],
+			expected: "invalid binary ranks: bad length for rank 2",
+		},
+		{
+			name:     "rank out of order",
+			data:     corrupt(func(data []byte) []byte { data[20] = 0; return data }),
+			expected: "invalid binary ranks: rank 0 out of order",
+		},
+		{
+			name:     "duplicate token",
+			data:     corrupt(func(data []byte) []byte { data[24] = 0; return data }),
+			expected: "invalid binary ranks: duplicate token for rank 1",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseBinaryRanks(tt.data)
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+
+	assert.EqualError(t, WriteBinaryRanks(&buf, map[string]uint{"a": 0, "b": 0}), "duplicate rank: 0")
+}
+
+func BenchmarkLoadRanks(b *testing.B) {
+	data, err := os.ReadFile("resource/cl100k_base.tiktoken")
+	require.NoError(b, err)
+
+	ranks, err := ConvertToMergeableBPERanks(bytes.NewReader(data))
+	require.NoError(b, err)
+
+	var buf bytes.Buffer
+	require.NoError(b, WriteBinaryRanks(&buf, ranks))
+
+	b.Run("tiktoken", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			_, _ = ConvertToMergeableBPERanks(bytes.NewReader(data))
+		}
+	})
+
+	b.Run("binary", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			_, _ = ParseBinaryRanks(buf.Bytes())
+		}
+	})
+}
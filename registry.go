@@ -0,0 +1,140 @@
+package tiktoken
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]func() (*Codec, error){}
+)
+
+// RegisterEncoding makes an encoding available under the given name to NewEncodingByName.
+// The newCodec function is called at most once per load of the encoding. Registering
+// a name twice returns an error.
+func RegisterEncoding(name string, newCodec func() (*Codec, error)) error {
+	if name == "" {
+		return errors.New("encoding name must not be empty")
+	}
+
+	if newCodec == nil {
+		return fmt.Errorf("nil codec constructor for encoding %s", name)
+	}
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	if _, ok := codecs[name]; ok {
+		return fmt.Errorf("encoding %s already registered", name)
+	}
+
+	codecs[name] = newCodec
+
+	return nil
+}
+
+// MustRegisterEncoding is like RegisterEncoding but panics if the encoding cannot be registered.
+// It is intended to be called from package init functions.
+func MustRegisterEncoding(name string, newCodec func() (*Codec, error)) {
+	if err := RegisterEncoding(name, newCodec); err != nil {
+		panic(err)
+	}
+}
+
+// ListEncodings returns the sorted names of all registered encodings.
+func ListEncodings() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// lookupCodec returns the codec constructor registered under the given name.
+func lookupCodec(name string) (func() (*Codec, error), bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	newCodec, ok := codecs[name]
+
+	return newCodec, ok
+}
+
+// encodingEntry holds a lazily built Encoding that is shared by all callers.
+type encodingEntry struct {
+	once     sync.Once
+	encoding *Encoding
+	err      error
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = map[string]*encodingEntry{}
+)
+
+// loadEncoding returns the cached Encoding for the given name, building it from the codec
+// returned by newCodec on first use. Concurrent callers for the same name wait for a single
+// build and receive the same instance. Failed builds are not cached.
+func loadEncoding(name string, newCodec func() (*Codec, error)) (*Encoding, error) {
+	encodingsMu.Lock()
+
+	entry, ok := encodings[name]
+	if !ok {
+		entry = &encodingEntry{}
+		encodings[name] = entry
+	}
+
+	encodingsMu.Unlock()
+
+	entry.once.Do(func() {
+		codec, err := newCodec()
+		if err != nil {
+			entry.err = err
+			return
+		}
+
+		entry.encoding, entry.err = NewEncoding(codec)
+	})
+
+	if entry.err != nil {
+		encodingsMu.Lock()
+		if encodings[name] == entry {
+			delete(encodings, name)
+		}
+		encodingsMu.Unlock()
+
+		return nil, entry.err
+	}
+
+	return entry.encoding, nil
+}
+
+// UnloadEncoding evicts the cached Encoding with the given name, if any.
+// Encodings that were already handed out stay valid; the next call to NewEncodingByName
+// builds a fresh instance. It reports whether an Encoding was evicted.
+func UnloadEncoding(name string) bool {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	_, ok := encodings[name]
+	delete(encodings, name)
+
+	return ok
+}
+
+// UnloadAllEncodings evicts all cached Encodings.
+func UnloadAllEncodings() {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	encodings = map[string]*encodingEntry{}
+}
@@ -0,0 +1,135 @@
+//go:build !tiktoken_minimal
+
+package tiktoken
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEncodingByNameCaching(t *testing.T) {
+	t.Run("shared instance", func(t *testing.T) {
+		first, err := NewEncodingByName(R50kBase)
+		require.NoError(t, err)
+
+		second, err := NewEncodingByName(R50kBase)
+		require.NoError(t, err)
+
+		assert.Same(t, first, second)
+	})
+
+	t.Run("concurrent callers", func(t *testing.T) {
+		UnloadEncoding(R50kBase)
+
+		const n = 8
+
+		var wg sync.WaitGroup
+
+		results := make([]*Encoding, n)
+
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				enc, err := NewEncodingByName(R50kBase)
+				assert.NoError(t, err)
+
+				results[i] = enc
+			}(i)
+		}
+
+		wg.Wait()
+
+		for _, enc := range results {
+			assert.Same(t, results[0], enc)
+		}
+	})
+
+	t.Run("unload", func(t *testing.T) {
+		first, err := NewEncodingByName(R50kBase)
+		require.NoError(t, err)
+
+		assert.True(t, UnloadEncoding(R50kBase))
+		assert.False(t, UnloadEncoding(R50kBase))
+
+		second, err := NewEncodingByName(R50kBase)
+		require.NoError(t, err)
+
+		assert.NotSame(t, first, second)
+		assert.Equal(t, "hello world", string(first.Decode([]uint{31373, 995})))
+	})
+}
+
+func TestLoadEncodingDoesNotCacheErrors(t *testing.T) {
+	calls := 0
+	newCodec := func() (*Codec, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("boom")
+		}
+
+		return &Codec{
+			Name:           "test",
+			PatStr:         `\S+|\s+`,
+			MergeableRanks: map[string]uint{"a": 0, "b": 1, "ab": 2},
+		}, nil
+	}
+
+	defer UnloadEncoding("test")
+
+	_, err := loadEncoding("test", newCodec)
+	assert.EqualError(t, err, "boom")
+
+	enc, err := loadEncoding("test", newCodec)
+	require.NoError(t, err)
+	assert.Equal(t, "test", enc.Name())
+	assert.Equal(t, 2, calls)
+}
+
+func TestRegisterEncoding(t *testing.T) {
+	newCodec := func() (*Codec, error) {
+		return &Codec{
+			Name:           "test-register",
+			PatStr:         `\S+|\s+`,
+			MergeableRanks: map[string]uint{"a": 0, "b": 1, "ab": 2},
+			SpecialTokens:  map[string]uint{EndOfText: 3},
+		}, nil
+	}
+
+	require.NoError(t, RegisterEncoding("test-register", newCodec))
+
+	t.Run("lookup by name", func(t *testing.T) {
+		enc, err := NewEncodingByName("test-register")
+		require.NoError(t, err)
+
+		ids, _ := enc.EncodeOrdinary("ab")
+		assert.Equal(t, []uint{2}, ids)
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		assert.EqualError(t, RegisterEncoding("test-register", newCodec), "encoding test-register already registered")
+		assert.Error(t, RegisterEncoding(CL100kBase, newCodec))
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		assert.Error(t, RegisterEncoding("", newCodec))
+		assert.Error(t, RegisterEncoding("test-nil", nil))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		names := ListEncodings()
+		assert.Subset(t, names, []string{O200kBase, CL100kBase, P50kBase, P50kEdit, R50kBase, GPT2, "test-register"})
+		assert.IsIncreasing(t, names)
+	})
+
+	t.Run("unknown encoding", func(t *testing.T) {
+		_, err := NewEncodingByName("unknown")
+		assert.EqualError(t, err, "unknown encoding: unknown")
+	})
+}
@@ -0,0 +1,229 @@
+// Package splitter splits texts into chunks bounded by their number of tokens, e.g. for embedding.
+package splitter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hupe1980/go-tiktoken"
+)
+
+// Chunk is a part of the split text.
+type Chunk struct {
+	Text   string
+	Start  int // byte offset of the chunk in the split text
+	End    int // byte offset of the end of the chunk in the split text
+	Tokens int // number of tokens of Text
+}
+
+// Option configures a Splitter.
+type Option func(*Splitter)
+
+// WithOverlap makes every chunk start with the given number of tokens from the end of the previous chunk.
+func WithOverlap(tokens int) Option {
+	return func(s *Splitter) {
+		s.overlap = tokens
+	}
+}
+
+// Splitter splits texts into chunks of at most a maximum number of tokens. It breaks chunks at
+// paragraph boundaries if possible, then at sentence boundaries, then at word boundaries, and
+// never inside a UTF-8 character. A single character that takes more tokens than the maximum
+// forms a chunk of its own that exceeds it. Special tokens are treated as ordinary text.
+// A Splitter is safe for concurrent use by multiple goroutines.
+type Splitter struct {
+	encoding  *tiktoken.Encoding
+	chunkSize int
+	overlap   int
+}
+
+// New returns a Splitter that splits texts into chunks of at most chunkSize tokens of the given Encoding.
+func New(encoding *tiktoken.Encoding, chunkSize int, optFns ...Option) (*Splitter, error) {
+	if encoding == nil {
+		return nil, errors.New("encoding must not be nil")
+	}
+
+	s := &Splitter{
+		encoding:  encoding,
+		chunkSize: chunkSize,
+	}
+
+	for _, fn := range optFns {
+		fn(s)
+	}
+
+	if s.chunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive: %d", s.chunkSize)
+	}
+
+	if s.overlap < 0 || s.overlap >= s.chunkSize {
+		return nil, fmt.Errorf("overlap must be between 0 and the chunk size: %d", s.overlap)
+	}
+
+	return s, nil
+}
+
+// NewForModel returns a Splitter for the encoding of the given model, e.g. text-embedding-3-small.
+func NewForModel(model string, chunkSize int, optFns ...Option) (*Splitter, error) {
+	encoding, err := tiktoken.NewEncodingForModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(encoding, chunkSize, optFns...)
+}
+
+// breakQuality ranks the places a chunk can end at.
+type breakQuality int
+
+const (
+	breakNone breakQuality = iota
+	breakWord
+	breakSentence
+	breakParagraph
+)
+
+// Split splits text into chunks. The chunks cover the whole text in order; without overlap,
+// concatenating their texts yields the original text. Every chunk has at most the chunk size
+// in tokens, except chunks of a single character that cannot be encoded in fewer tokens.
+func (s *Splitter) Split(text string) []Chunk {
+	_, offsets := s.encoding.EncodeOrdinaryWithOffsets(text)
+
+	var chunks []Chunk
+
+	for start := 0; start < len(offsets); {
+		end := s.chunkEnd(text, offsets, start)
+		chunk := s.newChunk(text, offsets, start, end)
+
+		// Encoding the chunk on its own can produce more tokens than its part of the whole text.
+		for chunk.Tokens > s.chunkSize {
+			prev := end - 1
+			for prev > start && !isCharBoundary(text, offsets[prev].Start) {
+				prev--
+			}
+
+			if prev == start {
+				break
+			}
+
+			end = prev
+			chunk = s.newChunk(text, offsets, start, end)
+		}
+
+		chunks = append(chunks, chunk)
+
+		if end == len(offsets) {
+			break
+		}
+
+		next := end - s.overlap
+		if next <= start {
+			next = start + 1
+		}
+
+		for next < end && !isCharBoundary(text, offsets[next].Start) {
+			next++
+		}
+
+		start = next
+	}
+
+	return chunks
+}
+
+// chunkEnd returns the index of the token after the last token of the chunk starting with the
+// token at index start.
+func (s *Splitter) chunkEnd(text string, offsets []tiktoken.TokenOffset, start int) int {
+	limit := start + s.chunkSize
+	if limit >= len(offsets) {
+		return len(offsets)
+	}
+
+	// Paragraph and sentence breaks are only preferred if they keep at least half of the chunk size.
+	minEnd := start + (s.chunkSize+1)/2
+
+	// ends[q] is the largest end with a break of at least quality q, or 0 if there is none.
+	var ends [breakParagraph + 1]int
+
+	for end := limit; end > start && ends[breakParagraph] == 0; end-- {
+		pos := offsets[end].Start
+		if !isCharBoundary(text, pos) {
+			continue
+		}
+
+		quality := breakAt(text, pos)
+		if end < minEnd && quality > breakWord {
+			quality = breakWord
+		}
+
+		for q := quality; q >= breakNone; q-- {
+			if ends[q] == 0 {
+				ends[q] = end
+			}
+		}
+	}
+
+	for q := breakParagraph; q >= breakNone; q-- {
+		if ends[q] != 0 {
+			return ends[q]
+		}
+	}
+
+	// The chunk is too small for a single character, so it ends after the character.
+	end := limit + 1
+	for end < len(offsets) && !isCharBoundary(text, offsets[end].Start) {
+		end++
+	}
+
+	return end
+}
+
+// newChunk returns the chunk of the tokens with indices in [start, end).
+func (s *Splitter) newChunk(text string, offsets []tiktoken.TokenOffset, start, end int) Chunk {
+	startPos := offsets[start].Start
+
+	endPos := len(text)
+	if end < len(offsets) {
+		endPos = offsets[end].Start
+	}
+
+	chunkText := text[startPos:endPos]
+
+	return Chunk{
+		Text:   chunkText,
+		Start:  startPos,
+		End:    endPos,
+		Tokens: s.encoding.CountOrdinary(chunkText),
+	}
+}
+
+// breakAt returns the quality of ending a chunk at the byte offset pos of text.
+func breakAt(text string, pos int) breakQuality {
+	before, after := text[:pos], text[pos:]
+
+	if strings.HasSuffix(before, "\n\n") || strings.HasPrefix(after, "\n\n") ||
+		strings.HasSuffix(before, "\r\n\r\n") || strings.HasPrefix(after, "\r\n\r\n") {
+		return breakParagraph
+	}
+
+	trimmed := strings.TrimRightFunc(before, unicode.IsSpace)
+	next, _ := utf8.DecodeRuneInString(after)
+
+	if last, _ := utf8.DecodeLastRuneInString(trimmed); strings.ContainsRune(".!?。！？", last) && (after == "" || trimmed != before || unicode.IsSpace(next)) {
+		return breakSentence
+	}
+
+	if prev, _ := utf8.DecodeLastRuneInString(before); unicode.IsSpace(prev) || unicode.IsSpace(next) {
+		return breakWord
+	}
+
+	return breakNone
+}
+
+// isCharBoundary reports whether the byte offset pos of text is not inside a UTF-8 character.
+func isCharBoundary(text string, pos int) bool {
+	return pos >= len(text) || utf8.RuneStart(text[pos])
+}
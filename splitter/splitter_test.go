@@ -0,0 +1,150 @@
+//go:build !tiktoken_minimal
+
+package splitter
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/hupe1980/go-tiktoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const document = `Tokenizers split text into tokens. Language models read tokens, not characters.
+
+Embedding models have a limited context window. Long documents must be split into chunks that fit into it.
+
+Good chunks end at paragraph boundaries. If a paragraph is too long, they end at sentence boundaries, and otherwise between words. Schöne Grüße 🙂 und 你好世界!`
+
+func chunkTexts(chunks []Chunk) []string {
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Text
+	}
+
+	return texts
+}
+
+func TestSplit(t *testing.T) {
+	encoding, err := tiktoken.NewEncodingByName(tiktoken.CL100kBase)
+	require.NoError(t, err)
+
+	t.Run("paragraphs", func(t *testing.T) {
+		s, err := New(encoding, 30)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			"Tokenizers split text into tokens. Language models read tokens, not characters.\n\n",
+			"Embedding models have a limited context window. Long documents must be split into chunks that fit into it.\n\n",
+			"Good chunks end at paragraph boundaries. If a paragraph is too long, they end at sentence boundaries, and otherwise between words.",
+			" Schöne Grüße 🙂 und 你好世界!",
+		}, chunkTexts(s.Split(document)))
+	})
+
+	t.Run("sentences", func(t *testing.T) {
+		s, err := New(encoding, 12)
+		require.NoError(t, err)
+
+		chunks := chunkTexts(s.Split(document))
+		assert.Equal(t, "Tokenizers split text into tokens.", chunks[0])
+		assert.Equal(t, " Language models read tokens, not characters.\n\n", chunks[1])
+	})
+
+	t.Run("words", func(t *testing.T) {
+		s, err := New(encoding, 3)
+		require.NoError(t, err)
+
+		chunks := chunkTexts(s.Split("one two three four five six seven"))
+		assert.Equal(t, []string{"one two three", " four five six", " seven"}, chunks)
+	})
+
+	t.Run("character larger than chunk", func(t *testing.T) {
+		s, err := New(encoding, 1)
+		require.NoError(t, err)
+
+		chunks := s.Split("a🙂b")
+		assert.Equal(t, []string{"a", "🙂", "b"}, chunkTexts(chunks))
+		assert.Equal(t, 1, chunks[0].Tokens)
+		assert.Greater(t, chunks[1].Tokens, 1)
+		assert.Equal(t, 1, chunks[2].Tokens)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		s, err := New(encoding, 10)
+		require.NoError(t, err)
+		assert.Empty(t, s.Split(""))
+	})
+}
+
+func TestSplitProperties(t *testing.T) {
+	encoding, err := tiktoken.NewEncodingByName(tiktoken.O200kBase)
+	require.NoError(t, err)
+
+	text := strings.Repeat(document+"\n\n", 5) + strings.Repeat("🙂", 50) + strings.Repeat("x", 200)
+
+	for _, chunkSize := range []int{4, 7, 16, 50, 200} {
+		s, err := New(encoding, chunkSize)
+		require.NoError(t, err)
+
+		chunks := s.Split(text)
+
+		var sb strings.Builder
+
+		for i, c := range chunks {
+			assert.Equal(t, text[c.Start:c.End], c.Text)
+			assert.True(t, utf8.ValidString(c.Text), "chunk %q", c.Text)
+			assert.LessOrEqual(t, c.Tokens, chunkSize, "chunk %q", c.Text)
+			assert.Equal(t, encoding.CountOrdinary(c.Text), c.Tokens)
+
+			if i > 0 {
+				assert.Equal(t, chunks[i-1].End, c.Start)
+			}
+
+			sb.WriteString(c.Text)
+		}
+
+		assert.Equal(t, text, sb.String(), "chunk size %d", chunkSize)
+	}
+}
+
+func TestSplitOverlap(t *testing.T) {
+	s, err := NewForModel("text-embedding-3-small", 20, WithOverlap(5))
+	require.NoError(t, err)
+
+	chunks := s.Split(document)
+	require.Greater(t, len(chunks), 2)
+
+	for i, c := range chunks {
+		assert.LessOrEqual(t, c.Tokens, 20)
+
+		if i > 0 {
+			prev := chunks[i-1]
+			assert.Less(t, c.Start, prev.End, "chunks %q and %q do not overlap", prev.Text, c.Text)
+			assert.Greater(t, c.Start, prev.Start)
+		}
+	}
+
+	assert.Equal(t, len(document), chunks[len(chunks)-1].End)
+}
+
+func TestNew(t *testing.T) {
+	encoding, err := tiktoken.NewEncodingByName(tiktoken.CL100kBase)
+	require.NoError(t, err)
+
+	_, err = New(nil, 10)
+	assert.Error(t, err)
+
+	_, err = New(encoding, 0)
+	assert.Error(t, err)
+
+	_, err = New(encoding, 10, WithOverlap(10))
+	assert.Error(t, err)
+
+	_, err = New(encoding, 10, WithOverlap(-1))
+	assert.Error(t, err)
+
+	_, err = NewForModel("unknown-model", 10)
+	assert.Error(t, err)
+}
@@ -2,10 +2,7 @@
 // The package includes various functions for text processing and encoding using the tiktoken algorithm.
 package tiktoken
 
-import (
-	"fmt"
-	"strings"
-)
+import "fmt"
 
 // Constants for different encodings.
 const (
@@ -15,85 +12,37 @@ const (
 	P50kEdit   string = "p50k_edit"
 	R50kBase   string = "r50k_base"
 	GPT2       string = "gpt2"
+	Claude     string = "claude"
 )
 
 // ModelPrefixToEncoding maps model prefixes to encodings.
-var ModelPrefixToEncoding = map[string]string{
-	"o1-": O200kBase,
-	// chat
-	"chatgpt-4o-":    O200kBase,
-	"gpt-4o-":        O200kBase,  // e.g., gpt-4o-2024-05-13
-	"gpt-4-":         CL100kBase, // e.g., gpt-4-0314, etc., plus gpt-4-32k
-	"gpt-3.5-turbo-": CL100kBase, // e.g, gpt-3.5-turbo-0301, -0401, etc.
-	"gpt-3.5":        CL100kBase, // Common shorthand
-	"gpt-35-turbo":   CL100kBase, // Azure deployment name
-
-	// fine-tuned
-	"ft:gpt-4":         CL100kBase,
-	"ft:gpt-3.5-turbo": CL100kBase,
-	"ft:davinci-002":   CL100kBase,
-	"ft:babbage-002":   CL100kBase,
-}
+//
+// Deprecated: Use LookupModel and RegisterModelPrefix. The map holds the built-in model families
+// of the registry. NewEncodingForModel honors changes to it, but LookupModel does not.
+var ModelPrefixToEncoding = modelEncodings(builtinModelPrefixes)
 
 // ModelToEncoding maps models to encodings.
-var ModelToEncoding = map[string]string{
-	// chat
-	"gpt-4o":        O200kBase,
-	"gpt-4":         CL100kBase,
-	"gpt-3.5-turbo": CL100kBase,
-	"gpt-35-turbo":  CL100kBase, // Azure deployment name
-	// text
-	"text-davinci-003": P50kBase,
-	"text-davinci-002": P50kBase,
-	"text-davinci-001": R50kBase,
-	"text-curie-001":   R50kBase,
-	"text-babbage-001": R50kBase,
-	"text-ada-001":     R50kBase,
-	"davinci":          R50kBase,
-	"curie":            R50kBase,
-	"babbage":          R50kBase,
-	"ada":              R50kBase,
-	// code
-	"code-davinci-002": P50kBase,
-	"code-davinci-001": P50kBase,
-	"code-cushman-002": P50kBase,
-	"code-cushman-001": P50kBase,
-	"davinci-codex":    P50kBase,
-	"cushman-codex":    P50kBase,
-	// edit
-	"text-davinci-edit-001": P50kEdit,
-	"code-davinci-edit-001": P50kEdit,
-	// embeddings
-	"text-embedding-ada-002": CL100kBase,
-	"text-embedding-3-small": CL100kBase,
-	"text-embedding-3-large": CL100kBase,
-	// old embeddings
-	"text-similarity-davinci-001":  R50kBase,
-	"text-similarity-curie-001":    R50kBase,
-	"text-similarity-babbage-001":  R50kBase,
-	"text-similarity-ada-001":      R50kBase,
-	"text-search-davinci-doc-001":  R50kBase,
-	"text-search-curie-doc-001":    R50kBase,
-	"text-search-babbage-doc-001":  R50kBase,
-	"text-search-ada-doc-001":      R50kBase,
-	"code-search-babbage-code-001": R50kBase,
-	"code-search-ada-code-001":     R50kBase,
-	// open source
-	"gpt2":  GPT2,
-	"gpt-2": GPT2, // Maintains consistency with gpt-4
+//
+// Deprecated: Use LookupModel and RegisterModel. The map holds the built-in models of the
+// registry. NewEncodingForModel honors changes to it, but LookupModel does not.
+var ModelToEncoding = modelEncodings(builtinModels)
+
+// modelEncodings maps the names of the given models to their encodings.
+func modelEncodings(infos []ModelInfo) map[string]string {
+	m := make(map[string]string, len(infos))
+	for _, info := range infos {
+		m[info.Name] = info.Encoding
+	}
+
+	return m
 }
 
 // NewEncodingForModel returns a new Encoding based on the given model.
-// It checks the ModelToEncoding map and ModelPrefixToEncoding map to find a matching encoding.
+// It looks the model up in the model registry like LookupModel, taking the built-in entries
+// from ModelToEncoding and ModelPrefixToEncoding.
 func NewEncodingForModel(model string) (*Encoding, error) {
-	if encoding, ok := ModelToEncoding[model]; ok {
+	if encoding, ok := modelEncoding(model); ok {
 		return NewEncodingByName(encoding)
-	} else {
-		for prefix, encoding := range ModelPrefixToEncoding {
-			if strings.HasPrefix(model, prefix) {
-				return NewEncodingByName(encoding)
-			}
-		}
 	}
 
 	return nil, fmt.Errorf("no encoding for model %s", model)
@@ -1,3 +1,5 @@
+//go:build !tiktoken_minimal
+
 package tiktoken
 
 import (
@@ -50,6 +52,12 @@ func TestNewEncodingForModel(t *testing.T) {
 			expectedResult: O200kBase,
 			expectedError:  nil,
 		},
+		{
+			name:           "claude",
+			model:          "claude-2.1",
+			expectedResult: Claude,
+			expectedError:  nil,
+		},
 		{
 			name:           "Model with Prefix",
 			model:          "gpt-4-",
@@ -0,0 +1,130 @@
+package tiktoken
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// TruncateStrategy determines which part of a text TruncateTokens removes.
+type TruncateStrategy int
+
+const (
+	// TruncateTail removes tokens from the end of the text and keeps its beginning.
+	TruncateTail TruncateStrategy = iota
+	// TruncateHead removes tokens from the beginning of the text and keeps its end.
+	TruncateHead
+	// TruncateMiddle removes tokens from the middle of the text and keeps its beginning and end.
+	TruncateMiddle
+)
+
+// String implements fmt.Stringer.
+func (s TruncateStrategy) String() string {
+	switch s {
+	case TruncateTail:
+		return "tail"
+	case TruncateHead:
+		return "head"
+	case TruncateMiddle:
+		return "middle"
+	default:
+		return fmt.Sprintf("TruncateStrategy(%d)", int(s))
+	}
+}
+
+// TruncateOption configures TruncateTokens.
+type TruncateOption func(*truncateOptions)
+
+type truncateOptions struct {
+	ellipsis string
+}
+
+// WithEllipsis inserts the given marker where text was removed. The tokens of the marker count
+// towards the token limit.
+func WithEllipsis(marker string) TruncateOption {
+	return func(o *truncateOptions) {
+		o.ellipsis = marker
+	}
+}
+
+// TruncateTokens shortens text to at most maxTokens tokens by removing tokens as determined by the
+// strategy. Special tokens are treated as ordinary text, like in EncodeOrdinary. The result is
+// guaranteed to encode to at most maxTokens tokens and never splits a UTF-8 character; text that
+// already fits is returned unchanged. It returns an error if maxTokens is negative or the ellipsis
+// alone exceeds maxTokens.
+func (enc *Encoding) TruncateTokens(text string, maxTokens int, strategy TruncateStrategy, optFns ...TruncateOption) (string, error) {
+	opts := truncateOptions{}
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	if maxTokens < 0 {
+		return "", fmt.Errorf("negative max tokens: %d", maxTokens)
+	}
+
+	switch strategy {
+	case TruncateTail, TruncateHead, TruncateMiddle:
+	default:
+		return "", fmt.Errorf("unsupported truncate strategy: %s", strategy)
+	}
+
+	_, offsets := enc.EncodeOrdinaryWithOffsets(text)
+	if len(offsets) <= maxTokens {
+		return text, nil
+	}
+
+	budget := maxTokens - enc.CountOrdinary(opts.ellipsis)
+	if budget < 0 {
+		return "", fmt.Errorf("ellipsis %q exceeds max tokens %d", opts.ellipsis, maxTokens)
+	}
+
+	// Cutting between tokens can change how the text around the cut is encoded, so the kept number
+	// of tokens is reduced until the result fits.
+	for keep := budget; ; keep-- {
+		result := truncateAt(text, offsets, keep, strategy, opts.ellipsis)
+		if keep == 0 || enc.CountOrdinary(result) <= maxTokens {
+			return result, nil
+		}
+	}
+}
+
+// truncateAt keeps keep of the tokens with the given offsets, as determined by the strategy, and
+// joins the kept parts of text with the ellipsis.
+func truncateAt(text string, offsets []TokenOffset, keep int, strategy TruncateStrategy, ellipsis string) string {
+	// headEnd returns the end of the first n tokens, moved back to a character boundary.
+	headEnd := func(n int) int {
+		if n == 0 {
+			return 0
+		}
+
+		end := offsets[n-1].End
+		for end > 0 && end < len(text) && !utf8.RuneStart(text[end]) {
+			end--
+		}
+
+		return end
+	}
+
+	// tailStart returns the start of the last n tokens, moved forward to a character boundary.
+	tailStart := func(n int) int {
+		if n == 0 {
+			return len(text)
+		}
+
+		start := offsets[len(offsets)-n].Start
+		for start < len(text) && !utf8.RuneStart(text[start]) {
+			start++
+		}
+
+		return start
+	}
+
+	switch strategy {
+	case TruncateHead:
+		return ellipsis + text[tailStart(keep):]
+	case TruncateMiddle:
+		head := keep - keep/2
+		return text[:headEnd(head)] + ellipsis + text[tailStart(keep/2):]
+	default:
+		return text[:headEnd(keep)] + ellipsis
+	}
+}
@@ -0,0 +1,100 @@
+//go:build !tiktoken_minimal
+
+package tiktoken
+
+import (
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateTokens(t *testing.T) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	require.NoError(t, err)
+
+	text := "The quick brown fox jumps over the lazy dog."
+
+	tests := []struct {
+		name      string
+		maxTokens int
+		strategy  TruncateStrategy
+		opts      []TruncateOption
+		expected  string
+	}{
+		{name: "fits", maxTokens: 10, strategy: TruncateTail, expected: text},
+		{name: "tail", maxTokens: 4, strategy: TruncateTail, expected: "The quick brown fox"},
+		{name: "head", maxTokens: 4, strategy: TruncateHead, expected: " the lazy dog."},
+		{name: "middle", maxTokens: 4, strategy: TruncateMiddle, expected: "The quick dog."},
+		{name: "tail with ellipsis", maxTokens: 5, strategy: TruncateTail, opts: []TruncateOption{WithEllipsis("...")}, expected: "The quick brown fox..."},
+		{name: "head with ellipsis", maxTokens: 5, strategy: TruncateHead, opts: []TruncateOption{WithEllipsis("...")}, expected: "... the lazy dog."},
+		{name: "middle with ellipsis", maxTokens: 5, strategy: TruncateMiddle, opts: []TruncateOption{WithEllipsis(" [...]")}, expected: "The quick [...] dog."},
+		{name: "zero", maxTokens: 0, strategy: TruncateMiddle, expected: ""},
+		{name: "only ellipsis", maxTokens: 1, strategy: TruncateTail, opts: []TruncateOption{WithEllipsis("...")}, expected: "..."},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := encoding.TruncateTokens(text, tt.maxTokens, tt.strategy, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+			assert.LessOrEqual(t, encoding.CountOrdinary(result), tt.maxTokens)
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := encoding.TruncateTokens(text, -1, TruncateTail)
+		assert.Error(t, err)
+
+		_, err = encoding.TruncateTokens(text, 1, TruncateTail, WithEllipsis(" [truncated]"))
+		assert.Error(t, err)
+
+		_, err = encoding.TruncateTokens(text, 1, TruncateStrategy(42))
+		assert.EqualError(t, err, "unsupported truncate strategy: TruncateStrategy(42)")
+	})
+
+	t.Run("multi-byte characters", func(t *testing.T) {
+		// every emoji is encoded as several tokens
+		emojis := strings.Repeat("🙂", 10)
+
+		for _, strategy := range []TruncateStrategy{TruncateTail, TruncateHead, TruncateMiddle} {
+			for maxTokens := 0; maxTokens < 20; maxTokens++ {
+				result, err := encoding.TruncateTokens(emojis, maxTokens, strategy)
+				require.NoError(t, err)
+				assert.True(t, utf8.ValidString(result), "%s %d: %q", strategy, maxTokens, result)
+				assert.LessOrEqual(t, encoding.CountOrdinary(result), maxTokens)
+			}
+		}
+	})
+}
+
+func TestTruncateTokensProperties(t *testing.T) {
+	for _, name := range []string{CL100kBase, O200kBase} {
+		encoding, err := NewEncodingByName(name)
+		require.NoError(t, err)
+
+		for _, strategy := range []TruncateStrategy{TruncateTail, TruncateHead, TruncateMiddle} {
+			strategy := strategy
+
+			t.Run(name+"/"+strategy.String(), func(t *testing.T) {
+				f := func(text textWithSpecials, n uint8) bool {
+					s := string(text)
+					maxTokens := int(n%32) + encoding.CountOrdinary("…")
+
+					result, err := encoding.TruncateTokens(s, maxTokens, strategy, WithEllipsis("…"))
+					require.NoError(t, err)
+
+					return assert.LessOrEqual(t, encoding.CountOrdinary(result), maxTokens, "text %q", s) &&
+						assert.True(t, utf8.ValidString(result), "text %q", s)
+				}
+
+				require.NoError(t, quick.Check(f, nil))
+			})
+		}
+	}
+}
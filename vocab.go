@@ -0,0 +1,66 @@
+package tiktoken
+
+// TokenBytes returns the bytes of the ordinary or special token with the given ID.
+// It reports false if the ID is not part of the Encoding.
+func (enc *Encoding) TokenBytes(id uint) ([]byte, bool) {
+	tokenBytes, ok := enc.coreBPE.tokenBytes(id)
+	if !ok {
+		return nil, false
+	}
+
+	return []byte(tokenBytes), true
+}
+
+// TokenID returns the ID of the ordinary or special token consisting of exactly the given bytes.
+// It reports false if the bytes are not a single token of the Encoding.
+func (enc *Encoding) TokenID(token []byte) (uint, bool) {
+	if id, ok := enc.coreBPE.encoder[string(token)]; ok {
+		return id, true
+	}
+
+	id, ok := enc.coreBPE.specialTokensEncoder[string(token)]
+
+	return id, ok
+}
+
+// IsSpecial reports whether the token with the given ID is a special token.
+func (enc *Encoding) IsSpecial(id uint) bool {
+	_, ok := enc.coreBPE.specialTokensDecoder[id]
+	return ok
+}
+
+// MaxTokenValue returns the largest token ID of the Encoding, including special tokens.
+func (enc *Encoding) MaxTokenValue() uint {
+	return enc.coreBPE.maxTokenValue
+}
+
+// VocabSize returns the size of the vocabulary, including special tokens, as MaxTokenValue() + 1.
+// Encodings with gaps in their token IDs contain fewer tokens.
+func (enc *Encoding) VocabSize() int {
+	return int(enc.coreBPE.maxTokenValue) + 1
+}
+
+// TokenByteValues returns the bytes of all ordinary tokens in lexicographical order.
+func (enc *Encoding) TokenByteValues() [][]byte {
+	values := make([][]byte, len(enc.coreBPE.sortedTokenBytes))
+	for i, v := range enc.coreBPE.sortedTokenBytes {
+		values[i] = append([]byte(nil), v...)
+	}
+
+	return values
+}
+
+// RangeTokens calls fn for every ordinary and special token in ascending order of their IDs.
+// If fn returns false, RangeTokens stops the iteration.
+func (enc *Encoding) RangeTokens(fn func(id uint, token []byte) bool) {
+	for id := uint(0); id <= enc.coreBPE.maxTokenValue; id++ {
+		tokenBytes, ok := enc.coreBPE.tokenBytes(id)
+		if !ok {
+			continue
+		}
+
+		if !fn(id, []byte(tokenBytes)) {
+			return
+		}
+	}
+}
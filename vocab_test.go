@@ -0,0 +1,91 @@
+//go:build !tiktoken_minimal
+
+package tiktoken
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVocabulary(t *testing.T) {
+	encoding, err := NewEncodingByName(CL100kBase)
+	require.NoError(t, err)
+
+	t.Run("token bytes", func(t *testing.T) {
+		b, ok := encoding.TokenBytes(9906)
+		assert.True(t, ok)
+		assert.Equal(t, []byte("Hello"), b)
+
+		b, ok = encoding.TokenBytes(100257)
+		assert.True(t, ok)
+		assert.Equal(t, []byte(EndOfText), b)
+
+		_, ok = encoding.TokenBytes(100256)
+		assert.False(t, ok)
+	})
+
+	t.Run("token id", func(t *testing.T) {
+		id, ok := encoding.TokenID([]byte("Hello"))
+		assert.True(t, ok)
+		assert.Equal(t, uint(9906), id)
+
+		id, ok = encoding.TokenID([]byte(EndOfText))
+		assert.True(t, ok)
+		assert.Equal(t, uint(100257), id)
+
+		_, ok = encoding.TokenID([]byte("Hello world"))
+		assert.False(t, ok)
+	})
+
+	t.Run("special", func(t *testing.T) {
+		assert.True(t, encoding.IsSpecial(100257))
+		assert.False(t, encoding.IsSpecial(9906))
+		assert.False(t, encoding.IsSpecial(1<<30))
+	})
+
+	t.Run("size", func(t *testing.T) {
+		assert.Equal(t, uint(100276), encoding.MaxTokenValue())
+		assert.Equal(t, 100277, encoding.VocabSize())
+	})
+
+	t.Run("token byte values", func(t *testing.T) {
+		values := encoding.TokenByteValues()
+		assert.Len(t, values, 100256)
+		assert.True(t, bytes.Compare(values[0], values[1]) < 0)
+	})
+
+	t.Run("range", func(t *testing.T) {
+		var (
+			count int
+			last  uint
+		)
+
+		encoding.RangeTokens(func(id uint, token []byte) bool {
+			if count > 0 {
+				assert.Greater(t, id, last)
+			}
+
+			b, _ := encoding.TokenBytes(id)
+			assert.Equal(t, b, token)
+
+			count++
+			last = id
+
+			return true
+		})
+
+		assert.Equal(t, 100256+5, count)
+		assert.Equal(t, encoding.MaxTokenValue(), last)
+
+		count = 0
+		encoding.RangeTokens(func(uint, []byte) bool {
+			count++
+			return count < 3
+		})
+
+		assert.Equal(t, 3, count)
+	})
+}